@@ -8,7 +8,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"net/url"
@@ -17,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -38,6 +38,8 @@ type CommandOptions struct {
 }
 
 type ConnectOptions struct {
+	// Context is used by the backends for their requests. When done, it also
+	// stops the retries and pending downloads of GetLedgers.
 	Context context.Context
 	// NetworkPassphrase defines the expected network of history archive. It is
 	// checked when getting HAS. If network passphrase does not match, error is
@@ -45,12 +47,35 @@ type ConnectOptions struct {
 	NetworkPassphrase string
 	S3Region          string
 	S3Endpoint        string
-	UnsignedRequests  bool
+	// GCSEndpoint overrides the Google Cloud Storage JSON API endpoint used
+	// for gcs:// URLs, for example to test against a local emulator. Files
+	// are downloaded from the host of the endpoint but always over https, so
+	// emulators serving plain http also need STORAGE_EMULATOR_HOST to be set
+	// to their host.
+	GCSEndpoint string
+	// UnsignedRequests makes S3 and GCS backends access public buckets
+	// without credentials.
+	UnsignedRequests bool
 	// CheckpointFrequency is the number of ledgers between checkpoints
 	// if unset, DefaultCheckpointFrequency will be used
 	CheckpointFrequency uint32
 	// UserAgent is the value of `User-Agent` header. Applicable only for HTTP client.
 	UserAgent string
+	// MaxRetries is the number of times a transient failure (network error,
+	// throttling or server error) fetching a file in GetLedgers is retried.
+	// If unset, failures are not retried.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled on every
+	// subsequent one. If unset, DefaultRetryBackoff will be used.
+	RetryBackoff time.Duration
+	// VerifyChecksums makes GetLedgers and StreamLedgers check the downloaded
+	// ledger headers, transaction sets and results against the hashes in the
+	// ledger headers and return ErrChecksumMismatch on failure.
+	VerifyChecksums bool
+	// PathPrefixer builds the paths of the checkpoint and bucket files, for
+	// archives which don't use the standard layout. If unset,
+	// StandardPathPrefixer will be used.
+	PathPrefixer PathPrefixer
 }
 
 type Ledger struct {
@@ -76,7 +101,10 @@ type ArchiveInterface interface {
 	CategoryCheckpointExists(cat string, chk uint32) (bool, error)
 	GetLedgerHeader(chk uint32) (xdr.LedgerHeaderHistoryEntry, error)
 	GetRootHAS() (HistoryArchiveState, error)
+	GetLatestLedgerSequence() (uint32, error)
 	GetLedgers(start, end uint32) (map[uint32]*Ledger, error)
+	StreamLedgers(ctx context.Context, start, end uint32) (<-chan LedgerOrError, error)
+	GetTransactionResults(start, end uint32) (map[uint32]xdr.TransactionHistoryResultEntry, error)
 	GetCheckpointHAS(chk uint32) (HistoryArchiveState, error)
 	PutCheckpointHAS(chk uint32, has HistoryArchiveState, opts *CommandOptions) error
 	PutRootHAS(has HistoryArchiveState, opts *CommandOptions) error
@@ -114,6 +142,17 @@ type Archive struct {
 
 	checkpointManager CheckpointManager
 
+	maxRetries   int
+	retryBackoff time.Duration
+
+	verifyChecksums bool
+
+	pathPrefixer PathPrefixer
+
+	// ctx is ConnectOptions.Context, which stops retries and pending
+	// downloads when done.
+	ctx context.Context
+
 	backend ArchiveBackend
 }
 
@@ -166,15 +205,15 @@ func (a *Archive) PutPathHAS(path string, has HistoryArchiveState, opts *Command
 }
 
 func (a *Archive) BucketExists(bucket Hash) (bool, error) {
-	return a.backend.Exists(BucketPath(bucket))
+	return a.backend.Exists(a.pathPrefixer.BucketPath(bucket))
 }
 
 func (a *Archive) BucketSize(bucket Hash) (int64, error) {
-	return a.backend.Size(BucketPath(bucket))
+	return a.backend.Size(a.pathPrefixer.BucketPath(bucket))
 }
 
 func (a *Archive) CategoryCheckpointExists(cat string, chk uint32) (bool, error) {
-	return a.backend.Exists(CategoryCheckpointPath(cat, chk))
+	return a.backend.Exists(a.pathPrefixer.CategoryCheckpointPath(cat, chk))
 }
 
 func (a *Archive) GetLedgerHeader(ledger uint32) (xdr.LedgerHeaderHistoryEntry, error) {
@@ -182,7 +221,7 @@ func (a *Archive) GetLedgerHeader(ledger uint32) (xdr.LedgerHeaderHistoryEntry,
 	if !a.checkpointManager.IsCheckpoint(checkpoint) {
 		checkpoint = a.checkpointManager.NextCheckpoint(ledger)
 	}
-	path := CategoryCheckpointPath("ledger", checkpoint)
+	path := a.pathPrefixer.CategoryCheckpointPath("ledger", checkpoint)
 	xdrStream, err := a.GetXdrStream(path)
 	if err != nil {
 		return xdr.LedgerHeaderHistoryEntry{}, errors.Wrap(err, "error opening ledger stream")
@@ -211,6 +250,21 @@ func (a *Archive) GetRootHAS() (HistoryArchiveState, error) {
 	return a.GetPathHAS(rootHASPath)
 }
 
+// GetLatestLedgerSequence returns the sequence of the latest ledger published
+// in the archive, as found in the root HAS.
+func (a *Archive) GetLatestLedgerSequence() (uint32, error) {
+	has, err := a.GetRootHAS()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not get root HAS")
+	}
+	return has.CurrentLedger, nil
+}
+
+// GetLedgers returns the ledgers of the whole checkpoints covering start to
+// min(end, latest ledger of the archive) keyed by sequence, so it may contain
+// ledgers outside of the range. If end is past the latest ledger of the
+// archive, the range ends with the latest ledger instead of failing; start
+// must be published.
 func (a *Archive) GetLedgers(start, end uint32) (map[uint32]*Ledger, error) {
 	if start > end {
 		return nil, errors.Errorf("range is invalid, start: %d end: %d", start, end)
@@ -219,15 +273,25 @@ func (a *Archive) GetLedgers(start, end uint32) (map[uint32]*Ledger, error) {
 	endCheckpoint := a.GetCheckpointManager().GetCheckpoint(end)
 	cache := map[uint32]*Ledger{}
 	for cur := startCheckpoint; cur <= endCheckpoint; cur += a.GetCheckpointManager().GetCheckpointFrequency() {
-		for _, category := range []string{"ledger", "transactions", "results"} {
-			if exists, err := a.CategoryCheckpointExists(category, cur); err != nil {
-				return nil, errors.Wrap(err, "could not check if category checkpoint exists")
-			} else if !exists {
-				return nil, errors.Errorf("checkpoint %d is not published", cur)
+		published, err := a.isCheckpointPublished(a.ctx, cur)
+		if err != nil {
+			return nil, err
+		} else if !published {
+			if cur > startCheckpoint && a.isBeyondLatestLedger(a.ctx, cur) {
+				// The range extends past the latest ledger of the archive
+				// so it ends with the previous checkpoint.
+				break
 			}
+			return nil, errors.Errorf("checkpoint %d is not published", cur)
+		}
+
+		if err := a.fetchCheckpoint(cache, cur); err != nil {
+			return nil, err
+		}
 
-			if err := a.fetchCategory(cache, category, cur); err != nil {
-				return nil, errors.Wrap(err, "could not fetch category checkpoint")
+		if a.verifyChecksums {
+			if err := a.verifyCheckpointLedgers(cache, cur); err != nil {
+				return nil, errors.Wrap(err, "could not verify checkpoint")
 			}
 		}
 	}
@@ -235,15 +299,161 @@ func (a *Archive) GetLedgers(start, end uint32) (map[uint32]*Ledger, error) {
 	return cache, nil
 }
 
-func (a *Archive) fetchCategory(cache map[uint32]*Ledger, category string, checkpointSequence uint32) error {
-	checkpointPath := CategoryCheckpointPath(category, checkpointSequence)
-	xdrStream, err := a.GetXdrStream(checkpointPath)
+// GetTransactionResults returns the transaction results of the ledgers from
+// start to end (inclusive) keyed by sequence. Only the results category is
+// downloaded, so it is much cheaper than GetLedgers when transaction sets and
+// headers aren't needed. Ledgers without transactions have no entry. Like
+// GetLedgers, the range ends with the latest ledger of the archive if end is
+// past it.
+func (a *Archive) GetTransactionResults(start, end uint32) (map[uint32]xdr.TransactionHistoryResultEntry, error) {
+	if start > end {
+		return nil, errors.Errorf("range is invalid, start: %d end: %d", start, end)
+	}
+	startCheckpoint := a.GetCheckpointManager().GetCheckpoint(start)
+	endCheckpoint := a.GetCheckpointManager().GetCheckpoint(end)
+	cache := map[uint32]*Ledger{}
+	for cur := startCheckpoint; cur <= endCheckpoint; cur += a.GetCheckpointManager().GetCheckpointFrequency() {
+		var exists bool
+		err := a.withRetry(a.ctx, func() error {
+			var err error
+			exists, err = a.CategoryCheckpointExists("results", cur)
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "could not check if category checkpoint exists")
+		} else if !exists {
+			if cur > startCheckpoint && a.isBeyondLatestLedger(a.ctx, cur) {
+				break
+			}
+			return nil, errors.Errorf("checkpoint %d is not published", cur)
+		}
+
+		err = a.withRetry(a.ctx, func() error {
+			return a.fetchCategory(a.ctx, cache, "results", cur)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "could not fetch category checkpoint")
+		}
+	}
+
+	results := make(map[uint32]xdr.TransactionHistoryResultEntry, len(cache))
+	for seq, ledger := range cache {
+		if seq >= start && seq <= end {
+			results[seq] = ledger.TransactionResult
+		}
+	}
+	return results, nil
+}
+
+// isCheckpointPublished returns true if the files of every category of the
+// checkpoint exist. Retries stop when ctx is done.
+func (a *Archive) isCheckpointPublished(ctx context.Context, checkpoint uint32) (bool, error) {
+	for _, category := range []string{"ledger", "transactions", "results"} {
+		var exists bool
+		err := a.withRetry(ctx, func() error {
+			var err error
+			exists, err = a.CategoryCheckpointExists(category, checkpoint)
+			return err
+		})
+		if err != nil {
+			return false, errors.Wrap(err, "could not check if category checkpoint exists")
+		} else if !exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isBeyondLatestLedger returns true if the checkpoint starts after the latest
+// ledger of the archive, i.e. it can't be published yet. It returns false if
+// the latest ledger can't be fetched.
+func (a *Archive) isBeyondLatestLedger(ctx context.Context, checkpoint uint32) bool {
+	var latest uint32
+	err := a.withRetry(ctx, func() error {
+		var err error
+		latest, err = a.GetLatestLedgerSequence()
+		return err
+	})
+	if err != nil {
+		return false
+	}
+	return a.checkpointManager.GetCheckpointRange(checkpoint).Low > latest
+}
+
+// fetchCheckpoint fetches the files of every category of the checkpoint in
+// parallel and merges them into cache. The first error cancels the other
+// fetches, including the ones waiting to be retried, and is returned.
+func (a *Archive) fetchCheckpoint(cache map[uint32]*Ledger, checkpoint uint32) error {
+	categories := []string{"ledger", "transactions", "results"}
+	caches := make([]map[uint32]*Ledger, len(categories))
+	ctx, cancel := context.WithCancel(a.ctx)
+	defer cancel()
+	var firstErr error
+	var firstErrOnce sync.Once
+	var wg sync.WaitGroup
+
+	for i, category := range categories {
+		i, category := i, category
+		caches[i] = map[uint32]*Ledger{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// fetchCategory only overwrites cache entries so a partially
+			// read file can be safely fetched again.
+			err := a.withRetry(ctx, func() error {
+				return a.fetchCategory(ctx, caches[i], category, checkpoint)
+			})
+			if err != nil {
+				firstErrOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return errors.Wrap(firstErr, "could not fetch category checkpoint")
+	}
+
+	for i, category := range categories {
+		for seq, fetched := range caches[i] {
+			entry := cache[seq]
+			if entry == nil {
+				entry = &Ledger{}
+				cache[seq] = entry
+			}
+			switch category {
+			case "ledger":
+				entry.Header = fetched.Header
+			case "transactions":
+				entry.Transaction = fetched.Transaction
+			case "results":
+				entry.TransactionResult = fetched.TransactionResult
+			}
+		}
+	}
+	return nil
+}
+
+// fetchCategory reads the file of a category of the checkpoint into cache. It
+// returns ctx.Err() as soon as ctx is done, which is checked between entries.
+func (a *Archive) fetchCategory(ctx context.Context, cache map[uint32]*Ledger, category string, checkpointSequence uint32) error {
+	checkpointPath := a.pathPrefixer.CategoryCheckpointPath(category, checkpointSequence)
+	xdrStream, err := a.getXdrStream(ctx, checkpointPath)
 	if err != nil {
 		return errors.Wrapf(err, "error opening %s stream", category)
 	}
 	defer xdrStream.Close()
 
 	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
 		switch category {
 		case "ledger":
 			var object xdr.LedgerHeaderHistoryEntry
@@ -290,11 +500,11 @@ func (a *Archive) fetchCategory(cache map[uint32]*Ledger, category string, check
 }
 
 func (a *Archive) GetCheckpointHAS(chk uint32) (HistoryArchiveState, error) {
-	return a.GetPathHAS(CategoryCheckpointPath("history", chk))
+	return a.GetPathHAS(a.pathPrefixer.CategoryCheckpointPath("history", chk))
 }
 
 func (a *Archive) PutCheckpointHAS(chk uint32, has HistoryArchiveState, opts *CommandOptions) error {
-	return a.PutPathHAS(CategoryCheckpointPath("history", chk), has, opts)
+	return a.PutPathHAS(a.pathPrefixer.CategoryCheckpointPath("history", chk), has, opts)
 }
 
 func (a *Archive) PutRootHAS(has HistoryArchiveState, opts *CommandOptions) error {
@@ -356,11 +566,7 @@ func (a *Archive) ListCategoryCheckpoints(cat string, pth string) (chan uint32,
 }
 
 func (a *Archive) GetBucketPathForHash(hash Hash) string {
-	return fmt.Sprintf(
-		"bucket/%s/bucket-%s.xdr.gz",
-		HashPrefix(hash).Path(),
-		hash.String(),
-	)
+	return a.pathPrefixer.BucketPath(hash)
 }
 
 func (a *Archive) GetXdrStreamForHash(hash Hash) (*XdrStream, error) {
@@ -378,9 +584,36 @@ func (a *Archive) GetXdrStream(pth string) (*XdrStream, error) {
 	return NewXdrGzStream(rdr)
 }
 
-func Connect(u string, opts ConnectOptions) (*Archive, error) {
+// getXdrStream is like GetXdrStream but returns ctx.Err() as soon as ctx is
+// done. Backends don't take a context per call, so a download in flight is
+// abandoned and its stream closed once it completes.
+func (a *Archive) getXdrStream(ctx context.Context, pth string) (*XdrStream, error) {
+	type result struct {
+		stream *XdrStream
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		stream, err := a.GetXdrStream(pth)
+		done <- result{stream, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.stream, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.stream != nil {
+				r.stream.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+func newArchive(networkPassphrase string, checkpointManager CheckpointManager) *Archive {
 	arch := Archive{
-		networkPassphrase:       opts.NetworkPassphrase,
+		networkPassphrase:       networkPassphrase,
 		checkpointFiles:         make(map[string](map[uint32]bool)),
 		allBuckets:              make(map[Hash]bool),
 		referencedBuckets:       make(map[Hash]bool),
@@ -390,19 +623,38 @@ func Connect(u string, opts ConnectOptions) (*Archive, error) {
 		actualTxSetHashes:       make(map[uint32]Hash),
 		expectTxResultSetHashes: make(map[uint32]Hash),
 		actualTxResultSetHashes: make(map[uint32]Hash),
-		checkpointManager:       NewCheckpointManager(opts.CheckpointFrequency),
+		checkpointManager:       checkpointManager,
+		pathPrefixer:            StandardPathPrefixer{},
+		ctx:                     context.Background(),
 	}
 	for _, cat := range Categories() {
 		arch.checkpointFiles[cat] = make(map[uint32]bool)
 	}
+	return &arch
+}
+
+func Connect(u string, opts ConnectOptions) (*Archive, error) {
+	arch := newArchive(opts.NetworkPassphrase, NewCheckpointManager(opts.CheckpointFrequency))
+	arch.maxRetries = opts.MaxRetries
+	arch.retryBackoff = opts.RetryBackoff
+	arch.verifyChecksums = opts.VerifyChecksums
+	if arch.retryBackoff == 0 {
+		arch.retryBackoff = DefaultRetryBackoff
+	}
+	if opts.PathPrefixer != nil {
+		arch.pathPrefixer = opts.PathPrefixer
+	}
+	if opts.Context != nil {
+		arch.ctx = opts.Context
+	}
 
 	if u == "" {
-		return &arch, errors.New("URL is empty")
+		return arch, errors.New("URL is empty")
 	}
 
 	parsed, err := url.Parse(u)
 	if err != nil {
-		return &arch, err
+		return arch, err
 	}
 
 	if opts.Context == nil {
@@ -416,6 +668,9 @@ func Connect(u string, opts ConnectOptions) (*Archive, error) {
 			pth = pth[1:]
 		}
 		arch.backend, err = makeS3Backend(parsed.Host, pth, opts)
+	} else if parsed.Scheme == "gcs" {
+		// Like s3, object names don't start with a /
+		arch.backend, err = makeGCSBackend(parsed.Host, strings.TrimPrefix(pth, "/"), opts)
 	} else if parsed.Scheme == "file" {
 		pth = path.Join(parsed.Host, pth)
 		arch.backend = makeFsBackend(pth, opts)
@@ -426,7 +681,20 @@ func Connect(u string, opts ConnectOptions) (*Archive, error) {
 	} else {
 		err = errors.New("unknown URL scheme: '" + parsed.Scheme + "'")
 	}
-	return &arch, err
+	return arch, err
+}
+
+// withBackend returns a new archive with the same options as a reading from
+// backend.
+func (a *Archive) withBackend(backend ArchiveBackend) *Archive {
+	arch := newArchive(a.networkPassphrase, a.checkpointManager)
+	arch.maxRetries = a.maxRetries
+	arch.retryBackoff = a.retryBackoff
+	arch.verifyChecksums = a.verifyChecksums
+	arch.pathPrefixer = a.pathPrefixer
+	arch.ctx = a.ctx
+	arch.backend = backend
+	return arch
 }
 
 func MustConnect(u string, opts ConnectOptions) *Archive {
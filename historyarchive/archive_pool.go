@@ -5,6 +5,7 @@
 package historyarchive
 
 import (
+	"context"
 	"math/rand"
 
 	"github.com/stellar/go/support/errors"
@@ -37,6 +38,12 @@ func NewArchivePool(archiveURLs []string, config ConnectOptions) (ArchivePool, e
 				NetworkPassphrase:   config.NetworkPassphrase,
 				CheckpointFrequency: config.CheckpointFrequency,
 				Context:             config.Context,
+				GCSEndpoint:         config.GCSEndpoint,
+				UnsignedRequests:    config.UnsignedRequests,
+				MaxRetries:          config.MaxRetries,
+				RetryBackoff:        config.RetryBackoff,
+				VerifyChecksums:     config.VerifyChecksums,
+				PathPrefixer:        config.PathPrefixer,
 			},
 		)
 
@@ -92,10 +99,22 @@ func (pa ArchivePool) GetRootHAS() (HistoryArchiveState, error) {
 	return pa.GetAnyArchive().GetRootHAS()
 }
 
+func (pa ArchivePool) GetLatestLedgerSequence() (uint32, error) {
+	return pa.GetAnyArchive().GetLatestLedgerSequence()
+}
+
+func (pa ArchivePool) GetTransactionResults(start, end uint32) (map[uint32]xdr.TransactionHistoryResultEntry, error) {
+	return pa.GetAnyArchive().GetTransactionResults(start, end)
+}
+
 func (pa ArchivePool) GetLedgers(start, end uint32) (map[uint32]*Ledger, error) {
 	return pa.GetAnyArchive().GetLedgers(start, end)
 }
 
+func (pa ArchivePool) StreamLedgers(ctx context.Context, start, end uint32) (<-chan LedgerOrError, error) {
+	return pa.GetAnyArchive().StreamLedgers(ctx, start, end)
+}
+
 func (pa ArchivePool) GetCheckpointHAS(chk uint32) (HistoryArchiveState, error) {
 	return pa.GetAnyArchive().GetCheckpointHAS(chk)
 }
@@ -7,18 +7,27 @@ package historyarchive
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math/big"
+	"net"
 	"os"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stellar/go/xdr"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func GetTestS3Archive() *Archive {
@@ -500,7 +509,7 @@ type xdrEntry interface {
 	MarshalBinary() ([]byte, error)
 }
 
-func writeCategoryFile(t *testing.T, backend ArchiveBackend, path string, entries []xdrEntry) {
+func writeCategoryFile(t testing.TB, backend ArchiveBackend, path string, entries []xdrEntry) {
 	file := &bytes.Buffer{}
 	writer := gzip.NewWriter(file)
 
@@ -617,3 +626,532 @@ func TestGetLedgers(t *testing.T) {
 		assertXdrEquals(t, results[i], ledger.TransactionResult)
 	}
 }
+
+// flakyBackend fails the first failures attempts to get a ledger category
+// file with err.
+type flakyBackend struct {
+	ArchiveBackend
+	failures int
+	err      error
+
+	mutex sync.Mutex
+	calls int
+}
+
+func (b *flakyBackend) GetFile(path string) (io.ReadCloser, error) {
+	if strings.HasPrefix(path, "ledger/") {
+		b.mutex.Lock()
+		b.calls++
+		fail := b.calls <= b.failures
+		b.mutex.Unlock()
+		if fail {
+			return nil, b.err
+		}
+	}
+	return b.ArchiveBackend.GetFile(path)
+}
+
+func getTestRetryArchive(t *testing.T, failures int, err error) (*Archive, *flakyBackend) {
+	archive := MustConnect("mock://test", ConnectOptions{
+		CheckpointFrequency: 64,
+		MaxRetries:          3,
+		RetryBackoff:        time.Millisecond,
+	})
+	writeCategoryFile(
+		t, archive.backend, "ledger/00/00/03/ledger-000003ff.xdr.gz",
+		[]xdrEntry{xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 1000}}},
+	)
+	writeCategoryFile(
+		t, archive.backend, "transactions/00/00/03/transactions-000003ff.xdr.gz",
+		[]xdrEntry{xdr.TransactionHistoryEntry{LedgerSeq: 1000}},
+	)
+	writeCategoryFile(
+		t, archive.backend, "results/00/00/03/results-000003ff.xdr.gz",
+		[]xdrEntry{xdr.TransactionHistoryResultEntry{LedgerSeq: 1000}},
+	)
+
+	backend := &flakyBackend{ArchiveBackend: archive.backend, failures: failures, err: err}
+	archive.backend = backend
+	return archive, backend
+}
+
+func TestGetLedgersRetriesTransientErrors(t *testing.T) {
+	archive, backend := getTestRetryArchive(t, 2, &net.OpError{Op: "read", Err: syscall.ECONNRESET})
+
+	ledgers, err := archive.GetLedgers(1000, 1000)
+	assert.NoError(t, err)
+	assert.Len(t, ledgers, 1)
+	assert.Equal(t, 3, backend.calls)
+}
+
+func TestGetLedgersGivesUpAfterMaxRetries(t *testing.T) {
+	archive, backend := getTestRetryArchive(t, 10, badResponseError{statusCode: 503, message: "unavailable"})
+
+	_, err := archive.GetLedgers(1000, 1000)
+	assert.EqualError(t, err, "could not fetch category checkpoint: error opening ledger stream: unavailable")
+	assert.Equal(t, 4, backend.calls)
+}
+
+func TestGetLedgersDoesNotRetryPermanentErrors(t *testing.T) {
+	archive, backend := getTestRetryArchive(t, 1, badResponseError{statusCode: 404, message: "not found"})
+
+	_, err := archive.GetLedgers(1000, 1000)
+	assert.EqualError(t, err, "could not fetch category checkpoint: error opening ledger stream: not found")
+	assert.Equal(t, 1, backend.calls)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	assert.True(t, isRetryableError(&net.OpError{Op: "read", Err: syscall.ECONNRESET}))
+	assert.True(t, isRetryableError(io.ErrUnexpectedEOF))
+	assert.True(t, isRetryableError(badResponseError{statusCode: 503}))
+	assert.True(t, isRetryableError(awserr.NewRequestFailure(awserr.New("InternalError", "", nil), 500, "")))
+	assert.True(t, isRetryableError(awserr.New("Throttling", "slow down", nil)))
+	assert.False(t, isRetryableError(badResponseError{statusCode: 404}))
+	assert.False(t, isRetryableError(awserr.NewRequestFailure(awserr.New("NoSuchKey", "", nil), 404, "")))
+	assert.False(t, isRetryableError(errors.New("no such file: ledger.xdr.gz")))
+	assert.False(t, isRetryableError(context.Canceled))
+}
+
+// slowBackend delays every file download and records the maximum number of
+// concurrent downloads.
+type slowBackend struct {
+	ArchiveBackend
+	delay time.Duration
+
+	mutex       sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (b *slowBackend) GetFile(path string) (io.ReadCloser, error) {
+	b.mutex.Lock()
+	b.inFlight++
+	if b.inFlight > b.maxInFlight {
+		b.maxInFlight = b.inFlight
+	}
+	b.mutex.Unlock()
+
+	time.Sleep(b.delay)
+
+	b.mutex.Lock()
+	b.inFlight--
+	b.mutex.Unlock()
+	return b.ArchiveBackend.GetFile(path)
+}
+
+func TestGetLedgersFetchesCategoriesInParallel(t *testing.T) {
+	archive := GetTestMockArchive()
+	writeTestCheckpoint(t, archive)
+	expected, err := archive.GetLedgers(960, 1023)
+	require.NoError(t, err)
+
+	backend := &slowBackend{ArchiveBackend: archive.backend, delay: 50 * time.Millisecond}
+	archive.backend = backend
+	ledgers, err := archive.GetLedgers(960, 1023)
+	require.NoError(t, err)
+	assert.Equal(t, 3, backend.maxInFlight)
+
+	require.Len(t, ledgers, 64)
+	for seq, ledger := range expected {
+		assertXdrEquals(t, ledger.Header, ledgers[seq].Header)
+		assertXdrEquals(t, ledger.Transaction, ledgers[seq].Transaction)
+		assertXdrEquals(t, ledger.TransactionResult, ledgers[seq].TransactionResult)
+	}
+}
+
+// failingBackend fails every attempt to get a file whose path starts with one
+// of the keys of errs with the corresponding error.
+type failingBackend struct {
+	ArchiveBackend
+	errs map[string]error
+}
+
+func (b *failingBackend) GetFile(path string) (io.ReadCloser, error) {
+	for prefix, err := range b.errs {
+		if strings.HasPrefix(path, prefix) {
+			return nil, err
+		}
+	}
+	return b.ArchiveBackend.GetFile(path)
+}
+
+func TestGetLedgersCancelsFetchesOnError(t *testing.T) {
+	archive := MustConnect("mock://test", ConnectOptions{
+		CheckpointFrequency: 64,
+		MaxRetries:          3,
+		RetryBackoff:        time.Minute,
+	})
+	writeTestCheckpoint(t, archive)
+	permanentErr := errors.New("permanent error")
+	archive.backend = &failingBackend{
+		ArchiveBackend: archive.backend,
+		errs: map[string]error{
+			// waits for a minute before being retried
+			"transactions/": &net.OpError{Op: "read", Err: syscall.ECONNRESET},
+			"ledger/":       permanentErr,
+		},
+	}
+
+	begin := time.Now()
+	_, err := archive.GetLedgers(1000, 1002)
+	assert.Less(t, time.Since(begin), 10*time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "permanent error")
+}
+
+func TestGetLedgersContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	archive := MustConnect("mock://test", ConnectOptions{
+		Context:             ctx,
+		CheckpointFrequency: 64,
+		MaxRetries:          3,
+		RetryBackoff:        time.Minute,
+	})
+	writeTestCheckpoint(t, archive)
+	archive.backend = &failingBackend{
+		ArchiveBackend: archive.backend,
+		errs:           map[string]error{"results/": &net.OpError{Op: "read", Err: syscall.ECONNRESET}},
+	}
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+	begin := time.Now()
+	_, err := archive.GetLedgers(1000, 1002)
+	assert.Less(t, time.Since(begin), 10*time.Second)
+	assert.Error(t, err)
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		delay := retryDelay(time.Second, attempt)
+		assert.GreaterOrEqual(t, delay, expected/2)
+		assert.LessOrEqual(t, delay, expected)
+	}
+	assert.LessOrEqual(t, retryDelay(time.Second, 100), maxRetryBackoff)
+}
+
+// writeTestCheckpoint publishes the ledger 1023 checkpoint, in which only
+// every other ledger has transactions.
+func writeTestCheckpoint(t testing.TB, archive *Archive) {
+	var ledgers, transactions, results []xdrEntry
+	for seq := uint32(960); seq <= 1023; seq++ {
+		ledgers = append(ledgers, xdr.LedgerHeaderHistoryEntry{
+			Hash:   xdr.Hash{byte(seq)},
+			Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(seq)},
+		})
+		if seq%2 == 1 {
+			continue
+		}
+		transactions = append(transactions, xdr.TransactionHistoryEntry{
+			LedgerSeq: xdr.Uint32(seq),
+			TxSet:     xdr.TransactionSet{PreviousLedgerHash: xdr.Hash{byte(seq - 1)}},
+		})
+		results = append(results, xdr.TransactionHistoryResultEntry{
+			LedgerSeq:   xdr.Uint32(seq),
+			TxResultSet: xdr.TransactionResultSet{Results: []xdr.TransactionResultPair{{TransactionHash: xdr.Hash{byte(seq)}}}},
+		})
+	}
+	writeCategoryFile(t, archive.backend, "ledger/00/00/03/ledger-000003ff.xdr.gz", ledgers)
+	writeCategoryFile(t, archive.backend, "transactions/00/00/03/transactions-000003ff.xdr.gz", transactions)
+	writeCategoryFile(t, archive.backend, "results/00/00/03/results-000003ff.xdr.gz", results)
+}
+
+func TestStreamLedgers(t *testing.T) {
+	archive := GetTestMockArchive()
+	_, err := archive.StreamLedgers(context.Background(), 1002, 1000)
+	assert.EqualError(t, err, "range is invalid, start: 1002 end: 1000")
+
+	ch, err := archive.StreamLedgers(context.Background(), 1000, 1002)
+	assert.NoError(t, err)
+	item, ok := <-ch
+	assert.True(t, ok)
+	assert.EqualError(t, item.Err, "checkpoint 1023 is not published")
+	_, ok = <-ch
+	assert.False(t, ok)
+
+	writeTestCheckpoint(t, archive)
+	expected, err := archive.GetLedgers(1000, 1010)
+	assert.NoError(t, err)
+
+	ch, err = archive.StreamLedgers(context.Background(), 1000, 1010)
+	assert.NoError(t, err)
+	seq := uint32(1000)
+	for item := range ch {
+		assert.NoError(t, item.Err)
+		assert.Equal(t, xdr.Uint32(seq), item.Ledger.Header.Header.LedgerSeq)
+		assertXdrEquals(t, expected[seq].Header, item.Ledger.Header)
+		assertXdrEquals(t, expected[seq].Transaction, item.Ledger.Transaction)
+		assertXdrEquals(t, expected[seq].TransactionResult, item.Ledger.TransactionResult)
+		seq++
+	}
+	assert.Equal(t, uint32(1011), seq)
+}
+
+func TestGetLedgersPastLatestLedger(t *testing.T) {
+	archive := GetTestMockArchive()
+	writeTestCheckpoint(t, archive)
+
+	// without a root HAS the latest ledger is unknown
+	_, err := archive.GetLatestLedgerSequence()
+	assert.Error(t, err)
+	_, err = archive.GetLedgers(1000, 1100)
+	assert.EqualError(t, err, "checkpoint 1087 is not published")
+
+	assert.NoError(t, archive.PutRootHAS(HistoryArchiveState{CurrentLedger: 1023}, nil))
+	latest, err := archive.GetLatestLedgerSequence()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1023), latest)
+
+	// whole checkpoints are returned
+	ledgers, err := archive.GetLedgers(1000, 1100)
+	assert.NoError(t, err)
+	assert.Len(t, ledgers, 64)
+	for seq := uint32(1000); seq <= 1023; seq++ {
+		assert.Contains(t, ledgers, seq)
+	}
+
+	ch, err := archive.StreamLedgers(context.Background(), 1000, 1100)
+	assert.NoError(t, err)
+	seq := uint32(1000)
+	for item := range ch {
+		assert.NoError(t, item.Err)
+		assert.Equal(t, xdr.Uint32(seq), item.Ledger.Header.Header.LedgerSeq)
+		seq++
+	}
+	assert.Equal(t, uint32(1024), seq)
+
+	// the start of the range must be published
+	_, err = archive.GetLedgers(1100, 1110)
+	assert.EqualError(t, err, "checkpoint 1151 is not published")
+	ch, err = archive.StreamLedgers(context.Background(), 1100, 1110)
+	assert.NoError(t, err)
+	item := <-ch
+	assert.EqualError(t, item.Err, "checkpoint 1151 is not published")
+}
+
+func TestGetLedgersZstd(t *testing.T) {
+	gzipArchive := GetTestMockArchive()
+	writeTestCheckpoint(t, gzipArchive)
+
+	// recompress the checkpoint files with zstd, keeping their names
+	zstdArchive := GetTestMockArchive()
+	for _, category := range []string{"ledger", "transactions", "results"} {
+		pth := CategoryCheckpointPath(category, 1023)
+		in, err := gzipArchive.backend.GetFile(pth)
+		require.NoError(t, err)
+		gzipReader, err := gzip.NewReader(in)
+		require.NoError(t, err)
+
+		file := &bytes.Buffer{}
+		writer, err := zstd.NewWriter(file)
+		require.NoError(t, err)
+		_, err = io.Copy(writer, gzipReader)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		require.NoError(t, in.Close())
+		require.NoError(t, zstdArchive.backend.PutFile(pth, ioutil.NopCloser(file)))
+	}
+
+	expected, err := gzipArchive.GetLedgers(960, 1023)
+	require.NoError(t, err)
+	actual, err := zstdArchive.GetLedgers(960, 1023)
+	require.NoError(t, err)
+	require.Len(t, actual, 64)
+	for seq, ledger := range expected {
+		assertXdrEquals(t, ledger.Header, actual[seq].Header)
+		assertXdrEquals(t, ledger.Transaction, actual[seq].Transaction)
+		assertXdrEquals(t, ledger.TransactionResult, actual[seq].TransactionResult)
+	}
+
+	ch, err := zstdArchive.StreamLedgers(context.Background(), 960, 1023)
+	require.NoError(t, err)
+	seq := uint32(960)
+	for item := range ch {
+		require.NoError(t, item.Err)
+		assertXdrEquals(t, expected[seq].Header, item.Ledger.Header)
+		seq++
+	}
+	assert.Equal(t, uint32(1024), seq)
+}
+
+func TestGetTransactionResults(t *testing.T) {
+	archive := GetTestMockArchive()
+	_, err := archive.GetTransactionResults(1010, 1000)
+	assert.EqualError(t, err, "range is invalid, start: 1010 end: 1000")
+	_, err = archive.GetTransactionResults(1000, 1010)
+	assert.EqualError(t, err, "checkpoint 1023 is not published")
+
+	// only the results category is needed
+	writeTestCheckpoint(t, archive)
+	backend := archive.backend.(*MockArchiveBackend)
+	delete(backend.files, "ledger/00/00/03/ledger-000003ff.xdr.gz")
+	delete(backend.files, "transactions/00/00/03/transactions-000003ff.xdr.gz")
+
+	results, err := archive.GetTransactionResults(1000, 1010)
+	require.NoError(t, err)
+	// only even ledgers have transactions
+	assert.Len(t, results, 6)
+	for seq := uint32(1000); seq <= 1010; seq += 2 {
+		assert.Equal(t, xdr.Uint32(seq), results[seq].LedgerSeq)
+		assert.Equal(t, xdr.Hash{byte(seq)}, results[seq].TxResultSet.Results[0].TransactionHash)
+	}
+}
+
+// shardedPathPrefixer stores all the files of a checkpoint in the same
+// directory, sharded by the checkpoint number.
+type shardedPathPrefixer struct{}
+
+func (shardedPathPrefixer) CategoryCheckpointPath(cat string, chk uint32) string {
+	return fmt.Sprintf("shards/%d/%08x/%s.%s", chk%4, chk, cat, categoryExt(cat))
+}
+
+func (shardedPathPrefixer) BucketPath(bucket Hash) string {
+	return fmt.Sprintf("buckets/%x/%s.xdr.gz", bucket[0], bucket)
+}
+
+func TestPathPrefixer(t *testing.T) {
+	archive := MustConnect("mock://test", ConnectOptions{
+		CheckpointFrequency: 64,
+		PathPrefixer:        shardedPathPrefixer{},
+	})
+	writeTestCheckpoint(t, archive)
+	backend := archive.backend.(*MockArchiveBackend)
+	for _, category := range []string{"ledger", "transactions", "results"} {
+		standardPath := CategoryCheckpointPath(category, 1023)
+		backend.files[shardedPathPrefixer{}.CategoryCheckpointPath(category, 1023)] = backend.files[standardPath]
+		delete(backend.files, standardPath)
+	}
+
+	ledgers, err := archive.GetLedgers(1000, 1010)
+	require.NoError(t, err)
+	assert.Len(t, ledgers, 64)
+	require.Contains(t, ledgers, uint32(1000))
+	assert.Equal(t, xdr.Uint32(1000), ledgers[1000].Header.Header.LedgerSeq)
+	assert.Equal(t, xdr.Uint32(1000), ledgers[1000].Transaction.LedgerSeq)
+
+	header, err := archive.GetLedgerHeader(1000)
+	require.NoError(t, err)
+	assert.Equal(t, xdr.Uint32(1000), header.Header.LedgerSeq)
+
+	has := HistoryArchiveState{CurrentLedger: 1023}
+	require.NoError(t, archive.PutCheckpointHAS(1023, has, testOptions()))
+	assert.Contains(t, backend.files, "shards/3/000003ff/history.json")
+	has, err = archive.GetCheckpointHAS(1023)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1023), has.CurrentLedger)
+
+	bucket := Hash{0xab, 0xcd}
+	assert.Equal(t, "buckets/ab/"+bucket.String()+".xdr.gz", archive.GetBucketPathForHash(bucket))
+	exists, err := archive.BucketExists(bucket)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, backend.PutFile(archive.GetBucketPathForHash(bucket), ioutil.NopCloser(bytes.NewReader(nil))))
+	exists, err = archive.BucketExists(bucket)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// the standard layout is used by default
+	standard := GetTestMockArchive()
+	assert.Equal(t, BucketPath(bucket), standard.GetBucketPathForHash(bucket))
+
+	pool, err := NewArchivePool([]string{"mock://test"}, ConnectOptions{
+		CheckpointFrequency: 64,
+		PathPrefixer:        shardedPathPrefixer{},
+	})
+	require.NoError(t, err)
+	require.Len(t, pool, 1)
+	pooled, ok := pool[0].(*Archive)
+	require.True(t, ok)
+	assert.Equal(t, "buckets/ab/"+bucket.String()+".xdr.gz", pooled.GetBucketPathForHash(bucket))
+	assert.Equal(t, "shards/3/000003ff/ledger.xdr.gz", pooled.pathPrefixer.CategoryCheckpointPath("ledger", 1023))
+}
+
+func TestStreamLedgersContextCanceled(t *testing.T) {
+	archive := GetTestMockArchive()
+	writeTestCheckpoint(t, archive)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := archive.StreamLedgers(ctx, 960, 1023)
+	assert.NoError(t, err)
+	item := <-ch
+	assert.NoError(t, item.Err)
+	cancel()
+
+	// the channel is closed before all the remaining ledgers are sent
+	count := 0
+	for range ch {
+		count++
+	}
+	assert.Less(t, count, 63)
+}
+
+// unreachableBackend fails every attempt to check if a file exists with err.
+type unreachableBackend struct {
+	ArchiveBackend
+	err error
+}
+
+func (b *unreachableBackend) Exists(path string) (bool, error) {
+	return false, b.err
+}
+
+func TestStreamLedgersContextCanceledWhileRetrying(t *testing.T) {
+	archive := MustConnect("mock://test", ConnectOptions{
+		CheckpointFrequency: 64,
+		MaxRetries:          3,
+		RetryBackoff:        time.Minute,
+	})
+	writeTestCheckpoint(t, archive)
+	archive.backend = &unreachableBackend{
+		ArchiveBackend: archive.backend,
+		// waits for a minute before being retried
+		err: &net.OpError{Op: "read", Err: syscall.ECONNRESET},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := archive.StreamLedgers(ctx, 960, 1023)
+	require.NoError(t, err)
+
+	// the stream stops retrying as soon as its context is canceled, even
+	// though the archive context is not
+	time.AfterFunc(100*time.Millisecond, cancel)
+	begin := time.Now()
+	for range ch {
+	}
+	assert.Less(t, time.Since(begin), 10*time.Second)
+}
+
+func BenchmarkGetLedgers(b *testing.B) {
+	archive := GetTestMockArchive()
+	writeTestCheckpoint(b, archive)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ledgers, err := archive.GetLedgers(960, 1023)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for seq := uint32(960); seq <= 1023; seq++ {
+			_ = ledgers[seq]
+		}
+	}
+}
+
+func BenchmarkStreamLedgers(b *testing.B) {
+	archive := GetTestMockArchive()
+	writeTestCheckpoint(b, archive)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ch, err := archive.StreamLedgers(context.Background(), 960, 1023)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for item := range ch {
+			if item.Err != nil {
+				b.Fatal(item.Err)
+			}
+		}
+	}
+}
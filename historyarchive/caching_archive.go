@@ -0,0 +1,255 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// NewCachingArchive returns an archive reading from the same backend as inner
+// which keeps the files it downloads in cacheDir and serves subsequent reads
+// of the same paths from there. Once the cached files take more than maxBytes
+// the least recently used ones are removed. The root HAS, which changes as the
+// archive is published to, is never cached.
+//
+// Files left in cacheDir by a previous caching archive are reused.
+func NewCachingArchive(inner *Archive, cacheDir string, maxBytes int64) (*Archive, error) {
+	backend, err := makeCachingBackend(inner.backend, cacheDir, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return inner.withBackend(backend), nil
+}
+
+// CachingArchiveBackend is an ArchiveBackend wrapping another one with a
+// least recently used disk cache. It is safe for concurrent use.
+type CachingArchiveBackend struct {
+	inner    ArchiveBackend
+	dir      string
+	maxBytes int64
+
+	mutex   sync.Mutex
+	size    int64
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+const cacheTempPrefix = ".tmp-"
+
+type cacheEntry struct {
+	name string
+	size int64
+}
+
+func makeCachingBackend(inner ArchiveBackend, dir string, maxBytes int64) (*CachingArchiveBackend, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "could not create cache directory")
+	}
+
+	b := &CachingArchiveBackend{
+		inner:    inner,
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  map[string]*list.Element{},
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read cache directory")
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(file.Name(), cacheTempPrefix) {
+			// left over by an interrupted store
+			os.Remove(filepath.Join(dir, file.Name()))
+			continue
+		}
+		b.add(file.Name(), file.Size())
+	}
+	b.mutex.Lock()
+	b.evict()
+	b.mutex.Unlock()
+
+	return b, nil
+}
+
+func (b *CachingArchiveBackend) GetFile(pth string) (io.ReadCloser, error) {
+	if !isCacheable(pth) {
+		return b.inner.GetFile(pth)
+	}
+
+	name := cacheName(pth)
+	if file, ok := b.open(name); ok {
+		log.WithField("path", pth).Trace("cache: hit")
+		return file, nil
+	}
+
+	log.WithField("path", pth).Trace("cache: miss")
+	rdr, err := b.inner.GetFile(pth)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+	buf, err := ioutil.ReadAll(rdr)
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(buf)) <= b.maxBytes {
+		if err := b.store(name, buf); err != nil {
+			log.WithField("path", pth).WithError(err).Warn("cache: could not store file")
+		}
+	}
+	return ioutil.NopCloser(bytes.NewReader(buf)), nil
+}
+
+func (b *CachingArchiveBackend) Exists(pth string) (bool, error) {
+	if isCacheable(pth) && b.contains(cacheName(pth)) {
+		return true, nil
+	}
+	return b.inner.Exists(pth)
+}
+
+func (b *CachingArchiveBackend) Size(pth string) (int64, error) {
+	return b.inner.Size(pth)
+}
+
+func (b *CachingArchiveBackend) PutFile(pth string, in io.ReadCloser) error {
+	b.remove(cacheName(pth))
+	return b.inner.PutFile(pth, in)
+}
+
+func (b *CachingArchiveBackend) ListFiles(pth string) (chan string, chan error) {
+	return b.inner.ListFiles(pth)
+}
+
+func (b *CachingArchiveBackend) CanListFiles() bool {
+	return b.inner.CanListFiles()
+}
+
+func isCacheable(pth string) bool {
+	return pth != rootHASPath
+}
+
+func cacheName(pth string) string {
+	sum := sha256.Sum256([]byte(pth))
+	return hex.EncodeToString(sum[:])
+}
+
+// open returns the cached file called name, if any, and marks it as the most
+// recently used one.
+func (b *CachingArchiveBackend) open(name string) (io.ReadCloser, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	elem, ok := b.entries[name]
+	if !ok {
+		return nil, false
+	}
+	file, err := os.Open(filepath.Join(b.dir, name))
+	if err != nil {
+		// the file was removed behind our back
+		b.removeElement(elem)
+		return nil, false
+	}
+	b.lru.MoveToFront(elem)
+	return file, true
+}
+
+func (b *CachingArchiveBackend) contains(name string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	_, ok := b.entries[name]
+	return ok
+}
+
+// store writes buf to the cache. The file is written under a temporary name
+// first so that concurrent readers never see a partial file.
+func (b *CachingArchiveBackend) store(name string, buf []byte) error {
+	tmp, err := ioutil.TempFile(b.dir, cacheTempPrefix+name)
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err = os.Rename(tmp.Name(), filepath.Join(b.dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if elem, ok := b.entries[name]; ok {
+		// stored concurrently by another reader
+		b.size -= elem.Value.(*cacheEntry).size
+		b.lru.Remove(elem)
+	}
+	b.entries[name] = b.lru.PushFront(&cacheEntry{name: name, size: int64(len(buf))})
+	b.size += int64(len(buf))
+	b.evict()
+	return nil
+}
+
+// add registers a file already in the cache directory as the most recently
+// used one.
+func (b *CachingArchiveBackend) add(name string, size int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.entries[name] = b.lru.PushFront(&cacheEntry{name: name, size: size})
+	b.size += size
+}
+
+func (b *CachingArchiveBackend) remove(name string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if elem, ok := b.entries[name]; ok {
+		b.removeElement(elem)
+	}
+}
+
+// evict removes the least recently used files until the cache fits in
+// maxBytes. It must be called with the mutex held.
+func (b *CachingArchiveBackend) evict() {
+	for b.size > b.maxBytes && b.lru.Len() > 0 {
+		b.removeElement(b.lru.Back())
+	}
+}
+
+// removeElement must be called with the mutex held.
+func (b *CachingArchiveBackend) removeElement(elem *list.Element) {
+	entry := b.lru.Remove(elem).(*cacheEntry)
+	delete(b.entries, entry.name)
+	b.size -= entry.size
+	if err := os.Remove(filepath.Join(b.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+		log.WithField("file", entry.name).WithError(err).Warn("cache: could not remove file")
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingBackend counts the reads made to the wrapped backend.
+type countingBackend struct {
+	ArchiveBackend
+	mutex  sync.Mutex
+	gets   map[string]int
+	exists int
+}
+
+func (b *countingBackend) GetFile(path string) (io.ReadCloser, error) {
+	b.mutex.Lock()
+	b.gets[path]++
+	b.mutex.Unlock()
+	return b.ArchiveBackend.GetFile(path)
+}
+
+func (b *countingBackend) Exists(path string) (bool, error) {
+	b.mutex.Lock()
+	b.exists++
+	b.mutex.Unlock()
+	return b.ArchiveBackend.Exists(path)
+}
+
+func (b *countingBackend) totalGets() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	total := 0
+	for _, count := range b.gets {
+		total += count
+	}
+	return total
+}
+
+func getTestCachingArchive(t *testing.T, dir string, maxBytes int64) (*Archive, *countingBackend) {
+	inner := GetTestMockArchive()
+	writeTestCheckpoint(t, inner)
+	backend := &countingBackend{ArchiveBackend: inner.backend, gets: map[string]int{}}
+	inner.backend = backend
+
+	archive, err := NewCachingArchive(inner, dir, maxBytes)
+	require.NoError(t, err)
+	return archive, backend
+}
+
+func readCachedFile(t *testing.T, archive *Archive, path string) []byte {
+	rdr, err := archive.backend.GetFile(path)
+	require.NoError(t, err)
+	defer rdr.Close()
+	buf, err := ioutil.ReadAll(rdr)
+	require.NoError(t, err)
+	return buf
+}
+
+func TestCachingArchiveGetLedgers(t *testing.T) {
+	archive, backend := getTestCachingArchive(t, t.TempDir(), 1<<20)
+
+	expected, err := archive.GetLedgers(1000, 1002)
+	require.NoError(t, err)
+	assert.Equal(t, 3, backend.totalGets())
+	assert.Equal(t, 3, backend.exists)
+
+	ledgers, err := archive.GetLedgers(1000, 1002)
+	require.NoError(t, err)
+	assert.Equal(t, 3, backend.totalGets())
+	assert.Equal(t, 3, backend.exists)
+
+	assert.Len(t, ledgers, len(expected))
+	for seq, ledger := range expected {
+		assertXdrEquals(t, ledger.Header, ledgers[seq].Header)
+		assertXdrEquals(t, ledger.Transaction, ledgers[seq].Transaction)
+		assertXdrEquals(t, ledger.TransactionResult, ledgers[seq].TransactionResult)
+	}
+}
+
+func TestCachingArchiveReusesCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	archive, _ := getTestCachingArchive(t, dir, 1<<20)
+	_, err := archive.GetLedgers(1000, 1002)
+	require.NoError(t, err)
+
+	archive, backend := getTestCachingArchive(t, dir, 1<<20)
+	_, err = archive.GetLedgers(1000, 1002)
+	require.NoError(t, err)
+	assert.Equal(t, 0, backend.totalGets())
+}
+
+func TestCachingArchiveEvictsLeastRecentlyUsed(t *testing.T) {
+	archive, backend := getTestCachingArchive(t, t.TempDir(), 250)
+	for _, path := range []string{"a", "b", "c", "big"} {
+		size := 100
+		if path == "big" {
+			size = 300
+		}
+		require.NoError(t, backend.ArchiveBackend.PutFile(path, ioutil.NopCloser(bytes.NewReader(make([]byte, size)))))
+	}
+
+	readCachedFile(t, archive, "a")
+	readCachedFile(t, archive, "b")
+	readCachedFile(t, archive, "a")
+	assert.Equal(t, map[string]int{"a": 1, "b": 1}, backend.gets)
+
+	// makes room by evicting b, which was used less recently than a
+	readCachedFile(t, archive, "c")
+	readCachedFile(t, archive, "a")
+	readCachedFile(t, archive, "b")
+	assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 1}, backend.gets)
+
+	// files larger than the cache are never cached
+	assert.Len(t, readCachedFile(t, archive, "big"), 300)
+	assert.Len(t, readCachedFile(t, archive, "big"), 300)
+	assert.Equal(t, 2, backend.gets["big"])
+}
+
+func TestCachingArchiveDoesNotCacheRootHAS(t *testing.T) {
+	archive, backend := getTestCachingArchive(t, t.TempDir(), 1<<20)
+	has := HistoryArchiveState{CurrentLedger: 1023}
+	require.NoError(t, archive.PutRootHAS(has, &CommandOptions{}))
+
+	for i := 0; i < 2; i++ {
+		actual, err := archive.GetRootHAS()
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1023), actual.CurrentLedger)
+	}
+	assert.Equal(t, 2, backend.gets[rootHASPath])
+}
+
+func TestCachingArchiveConcurrentReads(t *testing.T) {
+	archive, backend := getTestCachingArchive(t, t.TempDir(), 1<<20)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ledgers, err := archive.GetLedgers(1000, 1002)
+			assert.NoError(t, err)
+			assert.Len(t, ledgers, 64)
+		}()
+	}
+	wg.Wait()
+
+	_, err := archive.GetLedgers(1000, 1002)
+	require.NoError(t, err)
+	gets := backend.totalGets()
+	_, err = archive.GetLedgers(1000, 1002)
+	require.NoError(t, err)
+	assert.Equal(t, gets, backend.totalGets())
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// ErrChecksumMismatch is the cause of errors returned by GetLedgers and
+// StreamLedgers when ConnectOptions.VerifyChecksums is set and the downloaded
+// files do not match the hashes committed to in the ledger headers.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// verifyCheckpointLedgers checks the ledgers of a checkpoint fetched into
+// cache against the hashes in their headers: every header must hash to its
+// own hash and link to the previous one, and the transaction set and result
+// set of every ledger must hash to the values in its header.
+func (a *Archive) verifyCheckpointLedgers(cache map[uint32]*Ledger, checkpoint uint32) error {
+	verifier, err := newLedgerVerifier(a.GetCheckpointManager().GetCheckpointRange(checkpoint))
+	if err != nil {
+		return err
+	}
+	for seq := verifier.next; seq <= verifier.checkpointRange.High; seq++ {
+		ledger := cache[seq]
+		if ledger == nil {
+			return errors.Wrapf(ErrChecksumMismatch, "ledger %d: header is missing", seq)
+		}
+		if err := verifier.verify(*ledger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ledgerVerifier checks the ledgers of a checkpoint one at a time, in order,
+// so they can be verified while streamed. See verifyCheckpointLedgers.
+type ledgerVerifier struct {
+	checkpointRange    Range
+	emptyResultSetHash Hash
+	next               uint32
+	previous           *Ledger
+}
+
+func newLedgerVerifier(checkpointRange Range) (*ledgerVerifier, error) {
+	emptyResultSetHash, err := HashXdr(&xdr.TransactionResultSet{})
+	if err != nil {
+		return nil, err
+	}
+	return &ledgerVerifier{
+		checkpointRange:    checkpointRange,
+		emptyResultSetHash: emptyResultSetHash,
+		next:               checkpointRange.Low,
+	}, nil
+}
+
+// verify checks the next ledger of the checkpoint.
+func (v *ledgerVerifier) verify(ledger Ledger) error {
+	seq := v.next
+	header := ledger.Header
+	if uint32(header.Header.LedgerSeq) != seq {
+		return errors.Wrapf(ErrChecksumMismatch, "ledger %d: header is missing", seq)
+	}
+
+	h, err := HashXdr(&header.Header)
+	if err != nil {
+		return err
+	}
+	if h != Hash(header.Hash) {
+		return errors.Wrapf(ErrChecksumMismatch, "ledger %d: expected header hash %s, got %s",
+			seq, Hash(header.Hash), h)
+	}
+	if v.previous != nil && header.Header.PreviousLedgerHash != v.previous.Header.Hash {
+		return errors.Wrapf(ErrChecksumMismatch, "ledger %d: expected previous ledger hash %s, got %s",
+			seq, Hash(header.Header.PreviousLedgerHash), Hash(v.previous.Header.Hash))
+	}
+	v.previous = &ledger
+	v.next++
+
+	resultSetHash := v.emptyResultSetHash
+	if uint32(ledger.TransactionResult.LedgerSeq) == seq {
+		resultSetHash, err = HashXdr(&ledger.TransactionResult.TxResultSet)
+		if err != nil {
+			return err
+		}
+	}
+	if resultSetHash != Hash(header.Header.TxSetResultHash) {
+		return errors.Wrapf(ErrChecksumMismatch, "ledger %d: expected tx result set hash %s, got %s",
+			seq, Hash(header.Header.TxSetResultHash), resultSetHash)
+	}
+
+	// Ledgers without transactions have no entry in the transactions file.
+	// Their (empty) result set was checked above.
+	if uint32(ledger.Transaction.LedgerSeq) != seq {
+		return nil
+	}
+	txSetHash, err := hashTransactionHistoryEntry(ledger.Transaction)
+	if err != nil {
+		return err
+	}
+	if txSetHash != Hash(header.Header.ScpValue.TxSetHash) {
+		return errors.Wrapf(ErrChecksumMismatch, "ledger %d: expected tx set hash %s, got %s",
+			seq, Hash(header.Header.ScpValue.TxSetHash), txSetHash)
+	}
+	return nil
+}
+
+// done checks that every ledger of the checkpoint was verified.
+func (v *ledgerVerifier) done() error {
+	if v.next <= v.checkpointRange.High {
+		return errors.Wrapf(ErrChecksumMismatch, "ledger %d: header is missing", v.next)
+	}
+	return nil
+}
+
+// hashTransactionHistoryEntry returns the hash of the transaction set of the
+// entry without reordering the transactions of entry, which are stored in
+// apply order.
+func hashTransactionHistoryEntry(entry xdr.TransactionHistoryEntry) (Hash, error) {
+	if entry.Ext.V == 1 {
+		return HashXdr(entry.Ext.GeneralizedTxSet)
+	}
+	txSet := entry.TxSet
+	txSet.Txs = append([]xdr.TransactionEnvelope(nil), entry.TxSet.Txs...)
+	return HashTxSet(&txSet)
+}
@@ -0,0 +1,204 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// verifiableCheckpoint returns the entries of a valid first checkpoint in
+// which every third ledger has a transaction set.
+func verifiableCheckpoint(t *testing.T) ([]xdr.LedgerHeaderHistoryEntry, []xdr.TransactionHistoryEntry, []xdr.TransactionHistoryResultEntry) {
+	var (
+		headers      []xdr.LedgerHeaderHistoryEntry
+		transactions []xdr.TransactionHistoryEntry
+		results      []xdr.TransactionHistoryResultEntry
+	)
+	emptyResultSetHash, err := HashXdr(&xdr.TransactionResultSet{})
+	require.NoError(t, err)
+
+	var previousHash xdr.Hash
+	for seq := uint32(1); seq <= 63; seq++ {
+		header := xdr.LedgerHeader{
+			LedgerSeq:          xdr.Uint32(seq),
+			PreviousLedgerHash: previousHash,
+			TxSetResultHash:    xdr.Hash(emptyResultSetHash),
+		}
+		if seq%3 == 0 {
+			txSet := xdr.TransactionSet{PreviousLedgerHash: previousHash}
+			txSetHash, err := HashTxSet(&txSet)
+			require.NoError(t, err)
+			resultSet := xdr.TransactionResultSet{
+				Results: []xdr.TransactionResultPair{
+					{
+						TransactionHash: xdr.Hash{byte(seq)},
+						Result: xdr.TransactionResult{
+							Result: xdr.TransactionResultResult{Code: xdr.TransactionResultCodeTxBadSeq},
+						},
+					},
+				},
+			}
+			resultSetHash, err := HashXdr(&resultSet)
+			require.NoError(t, err)
+
+			header.ScpValue.TxSetHash = xdr.Hash(txSetHash)
+			header.TxSetResultHash = xdr.Hash(resultSetHash)
+			transactions = append(transactions, xdr.TransactionHistoryEntry{LedgerSeq: xdr.Uint32(seq), TxSet: txSet})
+			results = append(results, xdr.TransactionHistoryResultEntry{LedgerSeq: xdr.Uint32(seq), TxResultSet: resultSet})
+		}
+
+		hash, err := HashXdr(&header)
+		require.NoError(t, err)
+		headers = append(headers, xdr.LedgerHeaderHistoryEntry{Hash: xdr.Hash(hash), Header: header})
+		previousHash = xdr.Hash(hash)
+	}
+
+	return headers, transactions, results
+}
+
+func writeVerifiableCheckpoint(
+	t *testing.T,
+	archive *Archive,
+	headers []xdr.LedgerHeaderHistoryEntry,
+	transactions []xdr.TransactionHistoryEntry,
+	results []xdr.TransactionHistoryResultEntry,
+) {
+	var entries []xdrEntry
+	for _, entry := range headers {
+		entries = append(entries, entry)
+	}
+	writeCategoryFile(t, archive.backend, "ledger/00/00/00/ledger-0000003f.xdr.gz", entries)
+
+	entries = nil
+	for _, entry := range transactions {
+		entries = append(entries, entry)
+	}
+	writeCategoryFile(t, archive.backend, "transactions/00/00/00/transactions-0000003f.xdr.gz", entries)
+
+	entries = nil
+	for _, entry := range results {
+		entries = append(entries, entry)
+	}
+	writeCategoryFile(t, archive.backend, "results/00/00/00/results-0000003f.xdr.gz", entries)
+}
+
+func getTestVerifyingArchive() *Archive {
+	return MustConnect("mock://test", ConnectOptions{CheckpointFrequency: 64, VerifyChecksums: true})
+}
+
+func TestGetLedgersVerifyChecksums(t *testing.T) {
+	archive := getTestVerifyingArchive()
+	headers, transactions, results := verifiableCheckpoint(t)
+	writeVerifiableCheckpoint(t, archive, headers, transactions, results)
+
+	ledgers, err := archive.GetLedgers(1, 63)
+	require.NoError(t, err)
+	assert.Len(t, ledgers, 63)
+
+	streamed, err := streamAllLedgers(archive, 1, 63)
+	require.NoError(t, err)
+	assert.Len(t, streamed, 63)
+
+	// ledgers before the start of the range are verified but not sent
+	streamed, err = streamAllLedgers(archive, 10, 20)
+	require.NoError(t, err)
+	require.Len(t, streamed, 11)
+	assert.Equal(t, xdr.Uint32(10), streamed[0].Header.Header.LedgerSeq)
+}
+
+// streamAllLedgers returns the ledgers sent by StreamLedgers or the error
+// which ended the stream.
+func streamAllLedgers(archive *Archive, start, end uint32) ([]Ledger, error) {
+	ch, err := archive.StreamLedgers(context.Background(), start, end)
+	if err != nil {
+		return nil, err
+	}
+	var ledgers []Ledger
+	for item := range ch {
+		if item.Err != nil {
+			return ledgers, item.Err
+		}
+		ledgers = append(ledgers, item.Ledger)
+	}
+	return ledgers, nil
+}
+
+func TestGetLedgersChecksumMismatch(t *testing.T) {
+	for _, testCase := range []struct {
+		name          string
+		corrupt       func([]xdr.LedgerHeaderHistoryEntry, []xdr.TransactionHistoryEntry, []xdr.TransactionHistoryResultEntry) ([]xdr.LedgerHeaderHistoryEntry, []xdr.TransactionHistoryEntry, []xdr.TransactionHistoryResultEntry)
+		expectedError string
+	}{
+		{
+			name: "ledger header",
+			corrupt: func(h []xdr.LedgerHeaderHistoryEntry, tx []xdr.TransactionHistoryEntry, r []xdr.TransactionHistoryResultEntry) ([]xdr.LedgerHeaderHistoryEntry, []xdr.TransactionHistoryEntry, []xdr.TransactionHistoryResultEntry) {
+				h[10].Header.BaseFee ^= 1
+				return h, tx, r
+			},
+			expectedError: "ledger 11: expected header hash",
+		},
+		{
+			name: "missing ledger header",
+			corrupt: func(h []xdr.LedgerHeaderHistoryEntry, tx []xdr.TransactionHistoryEntry, r []xdr.TransactionHistoryResultEntry) ([]xdr.LedgerHeaderHistoryEntry, []xdr.TransactionHistoryEntry, []xdr.TransactionHistoryResultEntry) {
+				return h[:62], tx, r
+			},
+			expectedError: "ledger 63: header is missing",
+		},
+		{
+			name: "transaction set",
+			corrupt: func(h []xdr.LedgerHeaderHistoryEntry, tx []xdr.TransactionHistoryEntry, r []xdr.TransactionHistoryResultEntry) ([]xdr.LedgerHeaderHistoryEntry, []xdr.TransactionHistoryEntry, []xdr.TransactionHistoryResultEntry) {
+				tx[1].TxSet.PreviousLedgerHash[0] ^= 1
+				return h, tx, r
+			},
+			expectedError: "ledger 6: expected tx set hash",
+		},
+		{
+			name: "results",
+			corrupt: func(h []xdr.LedgerHeaderHistoryEntry, tx []xdr.TransactionHistoryEntry, r []xdr.TransactionHistoryResultEntry) ([]xdr.LedgerHeaderHistoryEntry, []xdr.TransactionHistoryEntry, []xdr.TransactionHistoryResultEntry) {
+				r[2].TxResultSet.Results[0].TransactionHash[0] ^= 1
+				return h, tx, r
+			},
+			expectedError: "ledger 9: expected tx result set hash",
+		},
+		{
+			name: "truncated results",
+			corrupt: func(h []xdr.LedgerHeaderHistoryEntry, tx []xdr.TransactionHistoryEntry, r []xdr.TransactionHistoryResultEntry) ([]xdr.LedgerHeaderHistoryEntry, []xdr.TransactionHistoryEntry, []xdr.TransactionHistoryResultEntry) {
+				return h, tx, r[:len(r)-1]
+			},
+			expectedError: "ledger 63: expected tx result set hash",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			headers, transactions, results := testCase.corrupt(verifiableCheckpoint(t))
+
+			archive := getTestVerifyingArchive()
+			writeVerifiableCheckpoint(t, archive, headers, transactions, results)
+			_, err := archive.GetLedgers(1, 63)
+			require.Error(t, err)
+			assert.Equal(t, ErrChecksumMismatch, errors.Cause(err))
+			assert.Contains(t, err.Error(), testCase.expectedError)
+
+			_, err = streamAllLedgers(archive, 1, 63)
+			require.Error(t, err)
+			assert.Equal(t, ErrChecksumMismatch, errors.Cause(err))
+			assert.Contains(t, err.Error(), testCase.expectedError)
+
+			// checksums are not verified by default
+			archive = GetTestMockArchive()
+			writeVerifiableCheckpoint(t, archive, headers, transactions, results)
+			_, err = archive.GetLedgers(1, 63)
+			assert.NoError(t, err)
+			_, err = streamAllLedgers(archive, 1, 63)
+			assert.NoError(t, err)
+		})
+	}
+}
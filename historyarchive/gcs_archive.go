@@ -0,0 +1,115 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"context"
+	"io"
+	"path"
+
+	"cloud.google.com/go/storage"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+type GCSArchiveBackend struct {
+	ctx    context.Context
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+func (b *GCSArchiveBackend) GetFile(pth string) (io.ReadCloser, error) {
+	key := path.Join(b.prefix, pth)
+	log.WithField("key", key).Trace("gcs: GetFile")
+	return b.bucket.Object(key).NewReader(b.ctx)
+}
+
+func (b *GCSArchiveBackend) Exists(pth string) (bool, error) {
+	key := path.Join(b.prefix, pth)
+	_, err := b.bucket.Object(key).Attrs(b.ctx)
+	if err == storage.ErrObjectNotExist {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *GCSArchiveBackend) Size(pth string) (int64, error) {
+	key := path.Join(b.prefix, pth)
+	attrs, err := b.bucket.Object(key).Attrs(b.ctx)
+	if err == storage.ErrObjectNotExist {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return attrs.Size, nil
+}
+
+func (b *GCSArchiveBackend) PutFile(pth string, in io.ReadCloser) error {
+	defer in.Close()
+	key := path.Join(b.prefix, pth)
+	w := b.bucket.Object(key).NewWriter(b.ctx)
+	if _, err := io.Copy(w, in); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (b *GCSArchiveBackend) ListFiles(pth string) (chan string, chan error) {
+	prefix := path.Join(b.prefix, pth)
+	ch := make(chan string)
+	errs := make(chan error)
+
+	go func() {
+		it := b.bucket.Objects(b.ctx, &storage.Query{Prefix: prefix})
+		for {
+			attrs, err := it.Next()
+			if err == iterator.Done {
+				break
+			} else if err != nil {
+				errs <- err
+				break
+			}
+			log.WithField("key", attrs.Name).Trace("gcs: ListFiles")
+			ch <- attrs.Name
+		}
+		close(ch)
+		close(errs)
+	}()
+	return ch, errs
+}
+
+func (b *GCSArchiveBackend) CanListFiles() bool {
+	return true
+}
+
+func makeGCSBackend(bucket string, prefix string, opts ConnectOptions) (ArchiveBackend, error) {
+	log.WithFields(log.Fields{"bucket": bucket,
+		"prefix":   prefix,
+		"endpoint": opts.GCSEndpoint}).Debug("gcs: making backend")
+
+	var clientOpts []option.ClientOption
+	if opts.GCSEndpoint != "" {
+		clientOpts = append(clientOpts, option.WithEndpoint(opts.GCSEndpoint))
+	}
+	if opts.UnsignedRequests {
+		clientOpts = append(clientOpts, option.WithoutAuthentication())
+	}
+
+	client, err := storage.NewClient(opts.Context, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := GCSArchiveBackend{
+		ctx:    opts.Context,
+		bucket: client.Bucket(bucket),
+		prefix: prefix,
+	}
+	return &backend, nil
+}
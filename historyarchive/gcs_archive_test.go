@@ -0,0 +1,127 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// createTestGCSBucket creates a bucket named after the test in the GCS
+// emulator (such as fake-gcs-server) running at ARCHIVIST_TEST_GCS_ENDPOINT,
+// for example http://localhost:4443/storage/v1/, and deletes it when the test
+// ends. It returns the endpoint and the bucket name. Since the emulator is
+// plain http, STORAGE_EMULATOR_HOST has to point to the same server so that
+// downloads also go to the emulator (see ConnectOptions.GCSEndpoint).
+func createTestGCSBucket(t *testing.T) (string, string) {
+	endpoint := os.Getenv("ARCHIVIST_TEST_GCS_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("ARCHIVIST_TEST_GCS_ENDPOINT not set")
+	}
+
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx, option.WithEndpoint(endpoint), option.WithoutAuthentication())
+	require.NoError(t, err)
+
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(t.Name()))
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	bucket := fmt.Sprintf("%s-%d", name, time.Now().UnixNano())
+	require.NoError(t, client.Bucket(bucket).Create(ctx, "test-project", nil))
+
+	t.Cleanup(func() {
+		defer client.Close()
+		it := client.Bucket(bucket).Objects(ctx, nil)
+		for {
+			attrs, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			require.NoError(t, err)
+			require.NoError(t, client.Bucket(bucket).Object(attrs.Name).Delete(ctx))
+		}
+		require.NoError(t, client.Bucket(bucket).Delete(ctx))
+	})
+	return endpoint, bucket
+}
+
+func connectTestGCSArchive(endpoint, bucket string) *Archive {
+	return MustConnect("gcs://"+bucket+"/archive", ConnectOptions{
+		Context:             context.Background(),
+		CheckpointFrequency: 64,
+		GCSEndpoint:         endpoint,
+		UnsignedRequests:    true,
+	})
+}
+
+// GetTestGCSArchive connects to a fresh bucket of the GCS emulator. See
+// createTestGCSBucket.
+func GetTestGCSArchive(t *testing.T) *Archive {
+	return connectTestGCSArchive(createTestGCSBucket(t))
+}
+
+func TestGCSGetLedgers(t *testing.T) {
+	archive := GetTestGCSArchive(t)
+	writeTestCheckpoint(t, archive)
+
+	ledgers, err := archive.GetLedgers(1000, 1002)
+	require.NoError(t, err)
+	assert.Len(t, ledgers, 64)
+	assert.Equal(t, uint32(1000), uint32(ledgers[1000].Header.Header.LedgerSeq))
+
+	exists, err := archive.CategoryCheckpointExists("ledger", 1087)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestGCSArchivePool(t *testing.T) {
+	endpoint, bucket := createTestGCSBucket(t)
+	writeTestCheckpoint(t, connectTestGCSArchive(endpoint, bucket))
+
+	pool, err := NewArchivePool(
+		[]string{"gcs://" + bucket + "/archive"},
+		ConnectOptions{
+			CheckpointFrequency: 64,
+			GCSEndpoint:         endpoint,
+			UnsignedRequests:    true,
+		},
+	)
+	require.NoError(t, err)
+
+	ledgers, err := pool.GetLedgers(1000, 1002)
+	require.NoError(t, err)
+	assert.Len(t, ledgers, 64)
+}
+
+func TestGCSGetRootHAS(t *testing.T) {
+	archive := GetTestGCSArchive(t)
+	has := HistoryArchiveState{
+		Version:       1,
+		Server:        "test",
+		CurrentLedger: 1023,
+	}
+	require.NoError(t, archive.PutRootHAS(has, &CommandOptions{}))
+
+	actual, err := archive.GetRootHAS()
+	require.NoError(t, err)
+	assert.Equal(t, has.CurrentLedger, actual.CurrentLedger)
+	assert.Equal(t, has.Server, actual.Server)
+}
@@ -22,12 +22,26 @@ type HttpArchiveBackend struct {
 	userAgent string
 }
 
+// badResponseError is returned for non-success HTTP responses so that the
+// status code can be inspected when deciding whether to retry.
+type badResponseError struct {
+	statusCode int
+	message    string
+}
+
+func (e badResponseError) Error() string {
+	return e.message
+}
+
 func checkResp(r *http.Response) error {
 	if r.StatusCode >= 200 && r.StatusCode < 400 {
 		return nil
 	} else {
-		return fmt.Errorf("Bad HTTP response '%s' for %s '%s'",
-			r.Status, r.Request.Method, r.Request.URL.String())
+		return badResponseError{
+			statusCode: r.StatusCode,
+			message: fmt.Sprintf("Bad HTTP response '%s' for %s '%s'",
+				r.Status, r.Request.Method, r.Request.URL.String()),
+		}
 	}
 }
 
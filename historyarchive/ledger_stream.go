@@ -0,0 +1,206 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"context"
+	"io"
+
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// LedgerOrError is a single value sent by StreamLedgers. Err is set only on
+// the last value sent before the channel is closed.
+type LedgerOrError struct {
+	Ledger Ledger
+	Err    error
+}
+
+// StreamLedgers is like GetLedgers but instead of loading entire checkpoints
+// into memory it sends ledgers from start to end (inclusive) in order, as they
+// are decoded from the category files. The returned channel is always closed:
+// after the last ledger, after an error or when ctx is done. Like GetLedgers,
+// the range ends with the latest ledger of the archive if end is past it.
+func (a *Archive) StreamLedgers(ctx context.Context, start, end uint32) (<-chan LedgerOrError, error) {
+	if start > end {
+		return nil, errors.Errorf("range is invalid, start: %d end: %d", start, end)
+	}
+
+	ch := make(chan LedgerOrError)
+	go func() {
+		defer close(ch)
+		if err := a.streamLedgers(ctx, start, end, ch); err != nil {
+			select {
+			case ch <- LedgerOrError{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (a *Archive) streamLedgers(ctx context.Context, start, end uint32, ch chan<- LedgerOrError) error {
+	manager := a.GetCheckpointManager()
+	startCheckpoint := manager.GetCheckpoint(start)
+	endCheckpoint := manager.GetCheckpoint(end)
+	for cur := startCheckpoint; cur <= endCheckpoint; cur += manager.GetCheckpointFrequency() {
+		published, err := a.isCheckpointPublished(ctx, cur)
+		if err != nil {
+			return err
+		} else if !published {
+			if cur > startCheckpoint && a.isBeyondLatestLedger(ctx, cur) {
+				return nil
+			}
+			return errors.Errorf("checkpoint %d is not published", cur)
+		}
+		if err := a.streamCheckpoint(ctx, cur, start, end, ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Archive) streamCheckpoint(ctx context.Context, checkpoint, start, end uint32, ch chan<- LedgerOrError) error {
+	streams, err := a.openLedgerStreams(ctx, checkpoint)
+	if err != nil {
+		return err
+	}
+	defer streams.Close()
+
+	var verifier *ledgerVerifier
+	if a.verifyChecksums {
+		verifier, err = newLedgerVerifier(a.GetCheckpointManager().GetCheckpointRange(checkpoint))
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		ledger, err := streams.Next()
+		if err == io.EOF {
+			if verifier != nil {
+				return errors.Wrap(verifier.done(), "could not verify checkpoint")
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// Ledgers are verified before they are sent, including the ones
+		// before start which link to the first ledger sent.
+		if verifier != nil {
+			if err := verifier.verify(ledger); err != nil {
+				return errors.Wrap(err, "could not verify checkpoint")
+			}
+		}
+
+		seq := uint32(ledger.Header.Header.LedgerSeq)
+		if seq < start {
+			continue
+		} else if seq > end {
+			return nil
+		}
+
+		select {
+		case ch <- LedgerOrError{Ledger: ledger}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (a *Archive) openLedgerStreams(ctx context.Context, checkpoint uint32) (*ledgerStreams, error) {
+	streams := &ledgerStreams{}
+	for _, category := range []string{"ledger", "transactions", "results"} {
+		var xdrStream *XdrStream
+		err := a.withRetry(ctx, func() error {
+			var err error
+			xdrStream, err = a.getXdrStream(ctx, a.pathPrefixer.CategoryCheckpointPath(category, checkpoint))
+			return err
+		})
+		if err != nil {
+			streams.Close()
+			return nil, errors.Wrapf(err, "error opening %s stream", category)
+		}
+
+		switch category {
+		case "ledger":
+			streams.ledgers = xdrStream
+		case "transactions":
+			streams.transactions = xdrStream
+		case "results":
+			streams.results = xdrStream
+		}
+	}
+	return streams, nil
+}
+
+// ledgerStreams reads the category files of a single checkpoint in lockstep.
+// Transactions and results files only contain entries for ledgers with
+// transactions so the next entry of each is held until the matching ledger
+// header is read.
+type ledgerStreams struct {
+	ledgers      *XdrStream
+	transactions *XdrStream
+	results      *XdrStream
+
+	nextTransaction  *xdr.TransactionHistoryEntry
+	nextResult       *xdr.TransactionHistoryResultEntry
+	transactionsDone bool
+	resultsDone      bool
+}
+
+// Next returns the next ledger of the checkpoint or io.EOF when all ledgers
+// have been read.
+func (s *ledgerStreams) Next() (Ledger, error) {
+	var ledger Ledger
+	if err := s.ledgers.ReadOne(&ledger.Header); err == io.EOF {
+		return ledger, err
+	} else if err != nil {
+		return ledger, errors.Wrap(err, "error reading from ledger stream")
+	}
+	seq := ledger.Header.Header.LedgerSeq
+
+	if s.nextTransaction == nil && !s.transactionsDone {
+		var entry xdr.TransactionHistoryEntry
+		if err := s.transactions.ReadOne(&entry); err == io.EOF {
+			s.transactionsDone = true
+		} else if err != nil {
+			return ledger, errors.Wrap(err, "error reading from transactions stream")
+		} else {
+			s.nextTransaction = &entry
+		}
+	}
+	if s.nextTransaction != nil && s.nextTransaction.LedgerSeq == seq {
+		ledger.Transaction = *s.nextTransaction
+		s.nextTransaction = nil
+	}
+
+	if s.nextResult == nil && !s.resultsDone {
+		var entry xdr.TransactionHistoryResultEntry
+		if err := s.results.ReadOne(&entry); err == io.EOF {
+			s.resultsDone = true
+		} else if err != nil {
+			return ledger, errors.Wrap(err, "error reading from results stream")
+		} else {
+			s.nextResult = &entry
+		}
+	}
+	if s.nextResult != nil && s.nextResult.LedgerSeq == seq {
+		ledger.TransactionResult = *s.nextResult
+		s.nextResult = nil
+	}
+
+	return ledger, nil
+}
+
+func (s *ledgerStreams) Close() {
+	for _, stream := range []*XdrStream{s.ledgers, s.transactions, s.results} {
+		if stream != nil {
+			stream.Close()
+		}
+	}
+}
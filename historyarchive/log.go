@@ -46,7 +46,7 @@ func (arch *Archive) MustGetBucketSize(hash Hash) int64 {
 }
 
 func (arch *Archive) MustGetLedgerHeaderHistoryEntries(chk uint32) []xdr.LedgerHeaderHistoryEntry {
-	path := CategoryCheckpointPath("ledger", chk)
+	path := arch.pathPrefixer.CategoryCheckpointPath("ledger", chk)
 	rdr, err := arch.GetXdrStream(path)
 	if err != nil {
 		panic(err)
@@ -68,7 +68,7 @@ func (arch *Archive) MustGetLedgerHeaderHistoryEntries(chk uint32) []xdr.LedgerH
 }
 
 func (arch *Archive) MustGetTransactionHistoryEntries(chk uint32) []xdr.TransactionHistoryEntry {
-	path := CategoryCheckpointPath("transactions", chk)
+	path := arch.pathPrefixer.CategoryCheckpointPath("transactions", chk)
 	rdr, err := arch.GetXdrStream(path)
 	if err != nil {
 		panic(err)
@@ -9,6 +9,7 @@ import (
 	"log"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/stellar/go/support/errors"
 )
@@ -30,12 +31,12 @@ func Mirror(src *Archive, dst *Archive, opts *CommandOptions) error {
 	var bucketFetchMutex sync.Mutex
 
 	var errs uint32
-	tick := makeTicker(func(ticks uint) {
+	progress := NewProgress(uint32(opts.Range.SizeInCheckPoints(src.checkpointManager)), nil)
+	tick := makeTicker(func(_ uint) {
 		bucketFetchMutex.Lock()
-		sz := opts.Range.SizeInCheckPoints(src.checkpointManager)
-		log.Printf("Copied %d/%d checkpoints (%f%%), %d buckets",
-			ticks, sz,
-			100.0*float64(ticks)/float64(sz),
+		done, total := progress.Done()
+		log.Printf("Copied %d/%d checkpoints (%f%%, %s remaining), %d buckets",
+			done, total, progress.Percent(), progress.Remaining().Round(time.Second),
 			len(bucketFetch))
 		bucketFetchMutex.Unlock()
 	})
@@ -87,6 +88,7 @@ func Mirror(src *Archive, dst *Archive, opts *CommandOptions) error {
 					}
 					atomic.AddUint32(&errs, noteError(err))
 				}
+				progress.Increment()
 				tick <- true
 			}
 			wg.Done()
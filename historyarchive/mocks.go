@@ -1,6 +1,8 @@
 package historyarchive
 
 import (
+	"context"
+
 	"github.com/stellar/go/xdr"
 	"github.com/stretchr/testify/mock"
 )
@@ -50,11 +52,26 @@ func (m *MockArchive) GetLedgers(start, end uint32) (map[uint32]*Ledger, error)
 	return a.Get(0).(map[uint32]*Ledger), a.Error(1)
 }
 
+func (m *MockArchive) GetTransactionResults(start, end uint32) (map[uint32]xdr.TransactionHistoryResultEntry, error) {
+	a := m.Called(start, end)
+	return a.Get(0).(map[uint32]xdr.TransactionHistoryResultEntry), a.Error(1)
+}
+
+func (m *MockArchive) StreamLedgers(ctx context.Context, start, end uint32) (<-chan LedgerOrError, error) {
+	a := m.Called(ctx, start, end)
+	return a.Get(0).(<-chan LedgerOrError), a.Error(1)
+}
+
 func (m *MockArchive) GetRootHAS() (HistoryArchiveState, error) {
 	a := m.Called()
 	return a.Get(0).(HistoryArchiveState), a.Error(1)
 }
 
+func (m *MockArchive) GetLatestLedgerSequence() (uint32, error) {
+	a := m.Called()
+	return a.Get(0).(uint32), a.Error(1)
+}
+
 func (m *MockArchive) GetCheckpointHAS(chk uint32) (HistoryArchiveState, error) {
 	a := m.Called(chk)
 	return a.Get(0).(HistoryArchiveState), a.Error(1)
@@ -0,0 +1,28 @@
+package historyarchive
+
+// PathPrefixer builds the paths, relative to the root of the archive, of the
+// checkpoint and bucket files of a history archive. It makes it possible to
+// read from mirrors which shard these files differently from the standard
+// Stellar layout.
+//
+// Only the files which are looked up by name use the PathPrefixer. Listing
+// and scanning the archive (ListBucket, ScanCheckpoints, Mirror, Repair...)
+// always assume the standard layout.
+type PathPrefixer interface {
+	CategoryCheckpointPath(cat string, chk uint32) string
+	BucketPath(bucket Hash) string
+}
+
+// StandardPathPrefixer is the PathPrefixer of the standard Stellar history
+// archive layout, e.g. ledger/00/00/03/ledger-000003ff.xdr.gz.
+type StandardPathPrefixer struct{}
+
+var _ PathPrefixer = StandardPathPrefixer{}
+
+func (StandardPathPrefixer) CategoryCheckpointPath(cat string, chk uint32) string {
+	return CategoryCheckpointPath(cat, chk)
+}
+
+func (StandardPathPrefixer) BucketPath(bucket Hash) string {
+	return BucketPath(bucket)
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// ProgressFunc is called with the number of checkpoints done out of the total
+// number of checkpoints of a range and the estimated time left to process the
+// rest (see Progress.Remaining).
+type ProgressFunc func(done, total uint32, remaining time.Duration)
+
+// Progress tracks the number of completed checkpoints of a range and
+// estimates the time left to process the rest. It is safe for concurrent use.
+type Progress struct {
+	mutex    sync.Mutex
+	total    uint32
+	done     uint32
+	start    time.Time
+	now      func() time.Time
+	callback ProgressFunc
+}
+
+// NewProgress returns a Progress for total checkpoints. callback, if not nil,
+// is called every time a checkpoint is done, with increasing done values.
+func NewProgress(total uint32, callback ProgressFunc) *Progress {
+	return &Progress{
+		total:    total,
+		start:    time.Now(),
+		now:      time.Now,
+		callback: callback,
+	}
+}
+
+// Increment marks one more checkpoint as done.
+func (p *Progress) Increment() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.done < p.total {
+		p.done++
+	}
+	if p.callback != nil {
+		p.callback(p.done, p.total, p.remaining())
+	}
+}
+
+// Done returns the number of checkpoints done and the total.
+func (p *Progress) Done() (uint32, uint32) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.done, p.total
+}
+
+// Percent returns the percentage of checkpoints done.
+func (p *Progress) Percent() float64 {
+	done, total := p.Done()
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(done) / float64(total)
+}
+
+// Remaining estimates the time left to process the remaining checkpoints
+// assuming they take as long as the ones done so far. It returns 0 before the
+// first checkpoint is done.
+func (p *Progress) Remaining() time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.remaining()
+}
+
+func (p *Progress) remaining() time.Duration {
+	if p.done == 0 {
+		return 0
+	}
+	elapsed := p.now().Sub(p.start)
+	perCheckpoint := elapsed / time.Duration(p.done)
+	return perCheckpoint * time.Duration(p.total-p.done)
+}
+
+// ForEachCheckpoint calls f for every checkpoint of rng from concurrency
+// goroutines and reports progress, if not nil, as checkpoints complete. It
+// stops at the first error returned by f and returns it.
+func (arch *Archive) ForEachCheckpoint(rng Range, concurrency int, progress ProgressFunc, f func(checkpoint uint32) error) error {
+	if concurrency <= 0 {
+		return errors.New("Zero concurrency")
+	}
+
+	tracker := NewProgress(uint32(rng.SizeInCheckPoints(arch.checkpointManager)), progress)
+	checkpoints := rng.GenerateCheckpoints(arch.checkpointManager)
+	stop := make(chan struct{})
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for checkpoint := range checkpoints {
+				select {
+				case <-stop:
+					continue // drain the remaining checkpoints
+				default:
+				}
+				if err := f(checkpoint); err != nil {
+					errOnce.Do(func() {
+						firstErr = errors.Wrapf(err, "error processing checkpoint %d", checkpoint)
+						close(stop)
+					})
+					continue
+				}
+				tracker.Increment()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
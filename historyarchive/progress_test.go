@@ -0,0 +1,100 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachCheckpoint(t *testing.T) {
+	archive := GetTestMockArchive()
+	rng := archive.checkpointManager.MakeRange(0, 64*20)
+
+	var (
+		mutex   sync.Mutex
+		visited = map[uint32]bool{}
+		updates []uint32
+		etas    []time.Duration
+	)
+	err := archive.ForEachCheckpoint(rng, 4,
+		func(done, total uint32, remaining time.Duration) {
+			assert.Equal(t, uint32(21), total)
+			updates = append(updates, done)
+			etas = append(etas, remaining)
+		},
+		func(checkpoint uint32) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			visited[checkpoint] = true
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	assert.Len(t, visited, 21)
+	for checkpoint := range rng.GenerateCheckpoints(archive.checkpointManager) {
+		assert.True(t, visited[checkpoint])
+	}
+	require.Len(t, updates, 21)
+	for i, done := range updates {
+		assert.Equal(t, uint32(i+1), done)
+	}
+	assert.Equal(t, time.Duration(0), etas[len(etas)-1])
+}
+
+func TestForEachCheckpointError(t *testing.T) {
+	archive := GetTestMockArchive()
+	rng := archive.checkpointManager.MakeRange(0, 64*20)
+
+	err := archive.ForEachCheckpoint(rng, 4, nil, func(checkpoint uint32) error {
+		if checkpoint == 63+64*5 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "error processing checkpoint 383: boom")
+
+	err = archive.ForEachCheckpoint(rng, 0, nil, func(uint32) error { return nil })
+	assert.EqualError(t, err, "Zero concurrency")
+}
+
+func TestProgressRemaining(t *testing.T) {
+	var reported []time.Duration
+	progress := NewProgress(4, func(done, total uint32, remaining time.Duration) {
+		reported = append(reported, remaining)
+	})
+	now := progress.start
+	progress.now = func() time.Time { return now }
+
+	assert.Equal(t, time.Duration(0), progress.Remaining())
+	assert.Equal(t, float64(0), progress.Percent())
+
+	now = now.Add(10 * time.Second)
+	progress.Increment()
+	assert.Equal(t, 30*time.Second, progress.Remaining())
+	assert.Equal(t, float64(25), progress.Percent())
+
+	now = now.Add(30 * time.Second)
+	progress.Increment()
+	progress.Increment()
+	progress.Increment()
+	assert.Equal(t, time.Duration(0), progress.Remaining())
+	assert.Equal(t, float64(100), progress.Percent())
+
+	// extra increments don't go past the total
+	progress.Increment()
+	done, total := progress.Done()
+	assert.Equal(t, uint32(4), done)
+	assert.Equal(t, uint32(4), total)
+
+	// the callback receives the estimate of Remaining
+	assert.Equal(t, []time.Duration{30 * time.Second, 40 * time.Second, 40 * time.Second / 3, 0, 0}, reported)
+}
@@ -37,6 +37,8 @@ func (c CheckpointManager) GetCheckpointFrequency() uint32 {
 	return c.checkpointFreq
 }
 
+// IsCheckpoint returns true if the given ledger sequence is the last ledger of
+// a checkpoint.
 func (c CheckpointManager) IsCheckpoint(i uint32) bool {
 	return (i+1)%c.checkpointFreq == 0
 }
@@ -60,12 +62,13 @@ func (c CheckpointManager) NextCheckpoint(i uint32) uint32 {
 	return uint32(ordered.Min(n, 0xffffffff))
 }
 
-// GetCheckPoint gets the checkpoint containing information about the given ledger sequence
+// GetCheckpoint gets the checkpoint containing information about the given ledger sequence
 func (c CheckpointManager) GetCheckpoint(i uint32) uint32 {
 	return c.NextCheckpoint(i)
 }
 
-// GetCheckpointRange gets the range of the checkpoint containing information for the given ledger sequence
+// GetCheckpointRange gets the range of the checkpoint containing information for the given ledger sequence.
+// Both ends are inclusive and the range of the first checkpoint starts at ledger 1.
 func (c CheckpointManager) GetCheckpointRange(i uint32) Range {
 	checkpoint := c.GetCheckpoint(i)
 	low := checkpoint - c.checkpointFreq + 1
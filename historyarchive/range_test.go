@@ -60,6 +60,41 @@ func TestRangeEnumeration(t *testing.T) {
 		mgr.MakeRange(0xff, 0x40).allCheckpoints())
 }
 
+func TestCheckpointManager(t *testing.T) {
+	mgr := NewCheckpointManager(0)
+	assert.Equal(t, DefaultCheckpointFrequency, mgr.GetCheckpointFrequency())
+
+	for _, testCase := range []struct {
+		frequency    uint32
+		ledger       uint32
+		checkpoint   uint32
+		rng          Range
+		isCheckpoint bool
+	}{
+		{64, 0, 63, Range{Low: 1, High: 63}, false},
+		{64, 1, 63, Range{Low: 1, High: 63}, false},
+		{64, 62, 63, Range{Low: 1, High: 63}, false},
+		{64, 63, 63, Range{Low: 1, High: 63}, true},
+		{64, 64, 127, Range{Low: 64, High: 127}, false},
+		{64, 127, 127, Range{Low: 64, High: 127}, true},
+		{64, 128, 191, Range{Low: 128, High: 191}, false},
+		{8, 1, 7, Range{Low: 1, High: 7}, false},
+		{8, 7, 7, Range{Low: 1, High: 7}, true},
+		{8, 8, 15, Range{Low: 8, High: 15}, false},
+		{8, 15, 15, Range{Low: 8, High: 15}, true},
+		{1, 1, 1, Range{Low: 1, High: 1}, true},
+		{1, 2, 2, Range{Low: 2, High: 2}, true},
+	} {
+		mgr := NewCheckpointManager(testCase.frequency)
+		assert.Equal(t, testCase.checkpoint, mgr.GetCheckpoint(testCase.ledger),
+			"frequency %d ledger %d", testCase.frequency, testCase.ledger)
+		assert.Equal(t, testCase.rng, mgr.GetCheckpointRange(testCase.ledger),
+			"frequency %d ledger %d", testCase.frequency, testCase.ledger)
+		assert.Equal(t, testCase.isCheckpoint, mgr.IsCheckpoint(testCase.ledger),
+			"frequency %d ledger %d", testCase.frequency, testCase.ledger)
+	}
+}
+
 func TestFmtRangeList(t *testing.T) {
 
 	mgr := NewCheckpointManager(64)
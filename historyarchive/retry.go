@@ -0,0 +1,97 @@
+// Copyright 2022 Stellar Development Foundation and contributors. Licensed
+// under the Apache License, Version 2.0. See the COPYING file at the root
+// of this distribution or at http://www.apache.org/licenses/LICENSE-2.0
+
+package historyarchive
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/googleapi"
+
+	"github.com/stellar/go/support/errors"
+)
+
+// DefaultRetryBackoff is the delay before the first retry when
+// ConnectOptions.MaxRetries is set but ConnectOptions.RetryBackoff is not.
+const DefaultRetryBackoff = time.Second
+
+// maxRetryBackoff caps the delay between two consecutive retries.
+const maxRetryBackoff = time.Minute
+
+// withRetry calls f until it succeeds, fails with an error which is not
+// transient or has been retried maxRetries times. Retries are delayed by a
+// jittered exponential backoff. If ctx is done while waiting to retry, the
+// last error of f is returned right away.
+func (a *Archive) withRetry(ctx context.Context, f func() error) error {
+	err := f()
+	for attempt := 0; err != nil && attempt < a.maxRetries && isRetryableError(err); attempt++ {
+		delay := retryDelay(a.retryBackoff, attempt)
+		log.WithField("err", err).Warnf("retrying in %s (%d/%d)", delay, attempt+1, a.maxRetries)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		err = f()
+	}
+	return err
+}
+
+// retryDelay returns a random delay in [d/2, d] where d is base doubled for
+// every previous attempt.
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// isRetryableError returns true for network errors, server side errors and
+// throttling, which are likely to go away when retried. Other errors, like a
+// missing file, are returned as is.
+func isRetryableError(err error) bool {
+	err = errors.Cause(err)
+
+	if err == io.ErrUnexpectedEOF {
+		// truncated download
+		return true
+	}
+
+	switch e := err.(type) {
+	case net.Error:
+		return true
+	case badResponseError:
+		return isRetryableStatusCode(e.statusCode)
+	case *googleapi.Error:
+		return isRetryableStatusCode(e.Code)
+	case awserr.Error:
+		if failure, ok := e.(awserr.RequestFailure); ok && isRetryableStatusCode(failure.StatusCode()) {
+			return true
+		}
+		return request.IsErrorRetryable(e) || request.IsErrorThrottle(e)
+	}
+
+	// The AWS SDK treats unknown errors as retryable so it is only
+	// consulted for its own errors.
+	return false
+}
+
+func isRetryableStatusCode(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
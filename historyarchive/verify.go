@@ -133,7 +133,7 @@ func (arch *Archive) VerifyCategoryCheckpoint(cat string, chk uint32) error {
 		return nil
 	}
 
-	rdr, err := arch.GetXdrStream(CategoryCheckpointPath(cat, chk))
+	rdr, err := arch.GetXdrStream(arch.pathPrefixer.CategoryCheckpointPath(cat, chk))
 	if err != nil {
 		return err
 	}
@@ -204,7 +204,7 @@ func checkBucketHash(hasher hash.Hash, expect Hash) error {
 }
 
 func (arch *Archive) VerifyBucketHash(h Hash) error {
-	rdr, err := arch.backend.GetFile(BucketPath(h))
+	rdr, err := arch.backend.GetFile(arch.pathPrefixer.BucketPath(h))
 	if err != nil {
 		return err
 	}
@@ -219,7 +219,7 @@ func (arch *Archive) VerifyBucketHash(h Hash) error {
 }
 
 func (arch *Archive) VerifyBucketEntries(h Hash) error {
-	rdr, err := arch.GetXdrStream(BucketPath(h))
+	rdr, err := arch.GetXdrStream(arch.pathPrefixer.BucketPath(h))
 	if err != nil {
 		return err
 	}
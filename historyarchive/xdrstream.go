@@ -15,6 +15,7 @@ import (
 	"io"
 	"io/ioutil"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stellar/go/support/errors"
 	"github.com/stellar/go/xdr"
 )
@@ -65,9 +66,27 @@ func NewXdrStream(in io.ReadCloser) *XdrStream {
 	}
 }
 
+// zstdMagic are the first bytes of a zstd frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// NewXdrGzStream returns a stream of the XDR entries of a compressed file. The
+// file is usually gzipped but zstd compressed files, recognized by their magic
+// number, are also supported.
 func NewXdrGzStream(in io.ReadCloser) (*XdrStream, error) {
 	gzipCountReader := newCountReader(in)
-	rdr, err := gzip.NewReader(bufReadCloser(gzipCountReader))
+	buffered := bufio.NewReader(gzipCountReader)
+
+	var rdr io.ReadCloser
+	var err error
+	if magic, _ := buffered.Peek(len(zstdMagic)); bytes.Equal(magic, zstdMagic) {
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(buffered, zstd.WithDecoderConcurrency(1))
+		if err == nil {
+			rdr = decoder.IOReadCloser()
+		}
+	} else {
+		rdr, err = gzip.NewReader(buffered)
+	}
 	if err != nil {
 		in.Close()
 		return nil, err
@@ -193,8 +212,8 @@ func (x *XdrStream) BytesRead() int64 {
 	return x.rdr.bytesRead
 }
 
-// GzipBytesRead returns the number of gzip bytes read in the stream.
-// Returns -1 if underlying reader is not gzipped.
+// GzipBytesRead returns the number of compressed (gzip or zstd) bytes read in
+// the stream. Returns -1 if underlying reader is not compressed.
 func (x *XdrStream) GzipBytesRead() int64 {
 	if x.gzipReader == nil {
 		return -1
@@ -1,7 +1,10 @@
 package ingest
 
 import (
+	"fmt"
+
 	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
 )
 
 // ErrNotFound is returned when the requested ledger is not found
@@ -18,3 +21,14 @@ type StateError struct {
 func NewStateError(err error) StateError {
 	return StateError{err}
 }
+
+// UnknownOperationTypeError is returned when an operation type is not known
+// by this version of the package, usually because it was introduced by a newer
+// protocol.
+type UnknownOperationTypeError struct {
+	Type xdr.OperationType
+}
+
+func (e UnknownOperationTypeError) Error() string {
+	return fmt.Sprintf("unknown operation type: %d", e.Type)
+}
@@ -0,0 +1,190 @@
+package ingest
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func paymentEnvelope(source, destination string, seq int64) xdr.TransactionEnvelope {
+	return xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{
+			Tx: xdr.Transaction{
+				SourceAccount: xdr.MustMuxedAddress(source),
+				SeqNum:        xdr.SequenceNumber(seq),
+				Operations: []xdr.Operation{
+					{
+						Body: xdr.OperationBody{
+							Type: xdr.OperationTypePayment,
+							PaymentOp: &xdr.PaymentOp{
+								Destination: xdr.MustMuxedAddress(destination),
+								Asset:       xdr.MustNewNativeAsset(),
+								Amount:      100,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ledgerCloseMetaFixtures returns the same ledger as a LedgerCloseMetaV0 and a
+// LedgerCloseMetaV1. Transactions are applied in a different order than the
+// one of the transaction set.
+func ledgerCloseMetaFixtures(t *testing.T) (xdr.LedgerCloseMeta, xdr.LedgerCloseMeta) {
+	envelopes := []xdr.TransactionEnvelope{
+		paymentEnvelope(participantsTxSource, participantsOther, 1),
+		paymentEnvelope(participantsOpSource, participantsThird, 2),
+		paymentEnvelope(participantsOther, participantsTxSource, 3),
+	}
+
+	var txProcessing []xdr.TransactionResultMeta
+	for _, i := range []int{2, 0, 1} {
+		hash, err := network.HashTransactionInEnvelope(envelopes[i], network.TestNetworkPassphrase)
+		require.NoError(t, err)
+		txProcessing = append(txProcessing, xdr.TransactionResultMeta{
+			Result: xdr.TransactionResultPair{
+				TransactionHash: hash,
+				Result: xdr.TransactionResult{
+					Result: xdr.TransactionResultResult{
+						Code:    xdr.TransactionResultCodeTxSuccess,
+						Results: &[]xdr.OperationResult{},
+					},
+				},
+			},
+			TxApplyProcessing: xdr.TransactionMeta{
+				V:  2,
+				V2: &xdr.TransactionMetaV2{},
+			},
+		})
+	}
+
+	header := xdr.LedgerHeaderHistoryEntry{
+		Hash: xdr.Hash{1, 2, 3},
+		Header: xdr.LedgerHeader{
+			LedgerSeq:     123,
+			LedgerVersion: 19,
+		},
+	}
+
+	v0 := xdr.LedgerCloseMeta{
+		V: 0,
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: header,
+			TxSet:        xdr.TransactionSet{Txs: envelopes},
+			TxProcessing: txProcessing,
+		},
+	}
+
+	baseFee := xdr.Int64(100)
+	v1 := xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			LedgerHeader: header,
+			TxSet: xdr.GeneralizedTransactionSet{
+				V: 1,
+				V1TxSet: &xdr.TransactionSetV1{
+					Phases: []xdr.TransactionPhase{
+						{
+							V: 0,
+							V0Components: &[]xdr.TxSetComponent{
+								{
+									Type: xdr.TxSetComponentTypeTxsetCompTxsMaybeDiscountedFee,
+									TxsMaybeDiscountedFee: &xdr.TxSetComponentTxsMaybeDiscountedFee{
+										BaseFee: &baseFee,
+										Txs:     envelopes[:1],
+									},
+								},
+								{
+									Type: xdr.TxSetComponentTypeTxsetCompTxsMaybeDiscountedFee,
+									TxsMaybeDiscountedFee: &xdr.TxSetComponentTxsMaybeDiscountedFee{
+										Txs: envelopes[1:],
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			TxProcessing: txProcessing,
+		},
+	}
+
+	return v0, v1
+}
+
+func readAllTransactions(t *testing.T, lcm xdr.LedgerCloseMeta) []LedgerTransaction {
+	reader, err := NewLedgerTransactionReaderFromLedgerCloseMeta(network.TestNetworkPassphrase, lcm)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(123), reader.GetSequence())
+	assert.Equal(t, xdr.Hash{1, 2, 3}, reader.GetHeader().Hash)
+
+	var transactions []LedgerTransaction
+	for {
+		tx, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		transactions = append(transactions, tx)
+	}
+	require.NoError(t, reader.Close())
+	return transactions
+}
+
+func TestLedgerTransactionReaderLedgerCloseMetaVersions(t *testing.T) {
+	v0, v1 := ledgerCloseMetaFixtures(t)
+
+	v0Transactions := readAllTransactions(t, v0)
+	v1Transactions := readAllTransactions(t, v1)
+	require.Len(t, v0Transactions, 3)
+	assert.Equal(t, v0Transactions, v1Transactions)
+
+	// transactions are read in apply order
+	var sources []string
+	for i, tx := range v0Transactions {
+		assert.Equal(t, uint32(i+1), tx.Index)
+		sources = append(sources, tx.Envelope.SourceAccount().ToAccountId().Address())
+
+		v0Participants, err := GetTransactionParticipants(tx)
+		require.NoError(t, err)
+		v1Participants, err := GetTransactionParticipants(v1Transactions[i])
+		require.NoError(t, err)
+		assert.Equal(t, addresses(v0Participants), addresses(v1Participants))
+	}
+	assert.Equal(t, []string{participantsOther, participantsTxSource, participantsOpSource}, sources)
+}
+
+func TestLedgerTransactionReaderCount(t *testing.T) {
+	v0, v1 := ledgerCloseMetaFixtures(t)
+	for _, lcm := range []xdr.LedgerCloseMeta{v0, v1} {
+		reader, err := NewLedgerTransactionReaderFromLedgerCloseMeta(network.TestNetworkPassphrase, lcm)
+		require.NoError(t, err)
+		assert.Equal(t, 3, reader.Count())
+
+		// reading doesn't change the count
+		_, err = reader.Read()
+		require.NoError(t, err)
+		assert.Equal(t, 3, reader.Count())
+	}
+
+	empty := xdr.LedgerCloseMeta{V: 0, V0: &xdr.LedgerCloseMetaV0{}}
+	reader, err := NewLedgerTransactionReaderFromLedgerCloseMeta(network.TestNetworkPassphrase, empty)
+	require.NoError(t, err)
+	assert.Equal(t, 0, reader.Count())
+}
+
+func TestLedgerTransactionReaderUnknownTransaction(t *testing.T) {
+	_, v1 := ledgerCloseMetaFixtures(t)
+	v1.V1.TxSet.V1TxSet.Phases[0] = xdr.TransactionPhase{V: 0, V0Components: &[]xdr.TxSetComponent{}}
+
+	_, err := NewLedgerTransactionReaderFromLedgerCloseMeta(network.TestNetworkPassphrase, v1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown tx hash in LedgerCloseMeta")
+}
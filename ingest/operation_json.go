@@ -0,0 +1,170 @@
+package ingest
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/stellar/go/amount"
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// operationTypeNames are the names Horizon uses for the operation types
+// supported by MarshalOperationJSON.
+var operationTypeNames = map[xdr.OperationType]string{
+	xdr.OperationTypeCreateAccount:            "create_account",
+	xdr.OperationTypePayment:                  "payment",
+	xdr.OperationTypePathPaymentStrictReceive: "path_payment_strict_receive",
+	xdr.OperationTypeAccountMerge:             "account_merge",
+	xdr.OperationTypePathPaymentStrictSend:    "path_payment_strict_send",
+}
+
+// MarshalOperationJSON returns the operation at index opIndex of the given
+// transaction as a JSON object with the same fields as Horizon's operation
+// resource. Amounts are decimal strings and assets are expanded into their
+// type, code and issuer. Muxed accounts are reported by their underlying G...
+// address along with `_muxed` and `_muxed_id` fields.
+//
+// Only payment operations (create account, payments, path payments and
+// account merges) are supported.
+func MarshalOperationJSON(transaction LedgerTransaction, opIndex int) ([]byte, error) {
+	operation, ok := transaction.GetOperation(uint32(opIndex))
+	if !ok {
+		return nil, errors.Errorf("operation index out of range: %d", opIndex)
+	}
+	typeName, ok := operationTypeNames[operation.Body.Type]
+	if !ok {
+		return nil, errors.Errorf("unsupported operation type: %d", operation.Body.Type)
+	}
+
+	source := operationSourceAccount(transaction, operation)
+	successful := transaction.Result.Successful()
+	details := map[string]interface{}{
+		"type":                   typeName,
+		"type_i":                 int32(operation.Body.Type),
+		"transaction_successful": successful,
+	}
+	addMuxedAccountJSON(details, source, "source_account")
+
+	switch operation.Body.Type {
+	case xdr.OperationTypeCreateAccount:
+		op := operation.Body.MustCreateAccountOp()
+		addMuxedAccountJSON(details, source, "funder")
+		details["account"] = op.Destination.Address()
+		details["starting_balance"] = amount.String(op.StartingBalance)
+	case xdr.OperationTypePayment:
+		op := operation.Body.MustPaymentOp()
+		addMuxedAccountJSON(details, source, "from")
+		addMuxedAccountJSON(details, op.Destination, "to")
+		details["amount"] = amount.String(op.Amount)
+		if err := addAssetJSON(details, op.Asset, ""); err != nil {
+			return nil, err
+		}
+	case xdr.OperationTypePathPaymentStrictReceive:
+		op := operation.Body.MustPathPaymentStrictReceiveOp()
+		addMuxedAccountJSON(details, source, "from")
+		addMuxedAccountJSON(details, op.Destination, "to")
+		details["amount"] = amount.String(op.DestAmount)
+		details["source_amount"] = amount.String(0)
+		details["source_max"] = amount.String(op.SendMax)
+		if err := addAssetJSON(details, op.DestAsset, ""); err != nil {
+			return nil, err
+		}
+		if err := addAssetJSON(details, op.SendAsset, "source_"); err != nil {
+			return nil, err
+		}
+		if successful {
+			result, err := operationResult(transaction, opIndex)
+			if err != nil {
+				return nil, err
+			}
+			pathPaymentResult := result.MustPathPaymentStrictReceiveResult()
+			details["source_amount"] = amount.String(pathPaymentResult.SendAmount())
+		}
+		path, err := pathJSON(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		details["path"] = path
+	case xdr.OperationTypePathPaymentStrictSend:
+		op := operation.Body.MustPathPaymentStrictSendOp()
+		addMuxedAccountJSON(details, source, "from")
+		addMuxedAccountJSON(details, op.Destination, "to")
+		details["amount"] = amount.String(0)
+		details["source_amount"] = amount.String(op.SendAmount)
+		details["destination_min"] = amount.String(op.DestMin)
+		if err := addAssetJSON(details, op.DestAsset, ""); err != nil {
+			return nil, err
+		}
+		if err := addAssetJSON(details, op.SendAsset, "source_"); err != nil {
+			return nil, err
+		}
+		if successful {
+			result, err := operationResult(transaction, opIndex)
+			if err != nil {
+				return nil, err
+			}
+			pathPaymentResult := result.MustPathPaymentStrictSendResult()
+			details["amount"] = amount.String(pathPaymentResult.DestAmount())
+		}
+		path, err := pathJSON(op.Path)
+		if err != nil {
+			return nil, err
+		}
+		details["path"] = path
+	case xdr.OperationTypeAccountMerge:
+		addMuxedAccountJSON(details, source, "account")
+		addMuxedAccountJSON(details, operation.Body.MustDestination(), "into")
+	}
+
+	return json.Marshal(details)
+}
+
+// operationResult returns the result of the operation at index opIndex of a
+// successful transaction.
+func operationResult(transaction LedgerTransaction, opIndex int) (xdr.OperationResultTr, error) {
+	results, ok := transaction.Result.OperationResults()
+	if !ok || opIndex >= len(results) {
+		return xdr.OperationResultTr{}, errors.Errorf("missing result for operation %d", opIndex)
+	}
+	return results[opIndex].MustTr(), nil
+}
+
+// addMuxedAccountJSON adds the G... address of the account as field. For
+// muxed accounts, the M... address and the id are also added in the `_muxed`
+// and `_muxed_id` fields. The id is a string since it doesn't fit in a
+// JavaScript number.
+func addMuxedAccountJSON(result map[string]interface{}, account xdr.MuxedAccount, field string) {
+	result[field] = account.ToAccountId().Address()
+	if account.Type == xdr.CryptoKeyTypeKeyTypeMuxedEd25519 {
+		result[field+"_muxed"] = account.Address()
+		result[field+"_muxed_id"] = strconv.FormatUint(uint64(account.Med25519.Id), 10)
+	}
+}
+
+// addAssetJSON adds the type, code and issuer of the asset as fields with the
+// given prefix. Native assets only have a type.
+func addAssetJSON(result map[string]interface{}, asset xdr.Asset, prefix string) error {
+	var assetType, code, issuer string
+	if err := asset.Extract(&assetType, &code, &issuer); err != nil {
+		return errors.Wrap(err, "xdr.Asset.Extract error")
+	}
+	result[prefix+"asset_type"] = assetType
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return nil
+	}
+	result[prefix+"asset_code"] = code
+	result[prefix+"asset_issuer"] = issuer
+	return nil
+}
+
+func pathJSON(path []xdr.Asset) ([]map[string]interface{}, error) {
+	out := make([]map[string]interface{}, len(path))
+	for i, asset := range path {
+		out[i] = map[string]interface{}{}
+		if err := addAssetJSON(out[i], asset, ""); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,141 @@
+package ingest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalOperationJSON(t *testing.T) {
+	muxedTxSource := xdr.MustMuxedAddress("MC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUAAAAAAAAAAAFIWY2")
+	muxedOther := xdr.MustMuxedAddress("MAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSAAAAAAAAAAE2LP26")
+	muxedOpSource := xdr.MustMuxedAddress("MDRW375MAYR46ODGF2WGANQC2RRZL7O246DYHHCGWTV2RE7IHE2QUAAAAAAAAAAAA5QJE")
+	opSource := xdr.MustMuxedAddress(participantsOpSource)
+	usd := xdr.MustNewCreditAsset("USD", participantsThird)
+	eur := xdr.MustNewCreditAsset("EUR", participantsThird)
+
+	for _, testCase := range []struct {
+		golden     string
+		operation  xdr.Operation
+		result     *xdr.OperationResult
+		successful bool
+	}{
+		{
+			golden: "create_account.json",
+			operation: xdr.Operation{
+				Body: xdr.OperationBody{
+					Type: xdr.OperationTypeCreateAccount,
+					CreateAccountOp: &xdr.CreateAccountOp{
+						Destination:     xdr.MustAddress(participantsOther),
+						StartingBalance: 100000000,
+					},
+				},
+			},
+			successful: true,
+		},
+		{
+			golden: "payment_muxed.json",
+			operation: xdr.Operation{
+				SourceAccount: &muxedTxSource,
+				Body: xdr.OperationBody{
+					Type: xdr.OperationTypePayment,
+					PaymentOp: &xdr.PaymentOp{
+						Destination: muxedOther,
+						Asset:       usd,
+						Amount:      12345678,
+					},
+				},
+			},
+			successful: true,
+		},
+		{
+			golden: "path_payment_strict_receive.json",
+			operation: xdr.Operation{
+				SourceAccount: &opSource,
+				Body: xdr.OperationBody{
+					Type: xdr.OperationTypePathPaymentStrictReceive,
+					PathPaymentStrictReceiveOp: &xdr.PathPaymentStrictReceiveOp{
+						SendAsset:   xdr.MustNewNativeAsset(),
+						SendMax:     50000000,
+						Destination: xdr.MustMuxedAddress(participantsOther),
+						DestAsset:   usd,
+						DestAmount:  10000000,
+						Path:        []xdr.Asset{eur},
+					},
+				},
+			},
+			result: &xdr.OperationResult{
+				Code: xdr.OperationResultCodeOpInner,
+				Tr: &xdr.OperationResultTr{
+					Type: xdr.OperationTypePathPaymentStrictReceive,
+					PathPaymentStrictReceiveResult: &xdr.PathPaymentStrictReceiveResult{
+						Code: xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSuccess,
+						Success: &xdr.PathPaymentStrictReceiveResultSuccess{
+							Last: xdr.SimplePaymentResult{Amount: 30000000},
+						},
+					},
+				},
+			},
+			successful: true,
+		},
+		{
+			golden: "path_payment_strict_send_failed.json",
+			operation: xdr.Operation{
+				Body: xdr.OperationBody{
+					Type: xdr.OperationTypePathPaymentStrictSend,
+					PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{
+						SendAsset:   usd,
+						SendAmount:  20000000,
+						Destination: xdr.MustMuxedAddress(participantsOther),
+						DestAsset:   xdr.MustNewNativeAsset(),
+						DestMin:     10000000,
+					},
+				},
+			},
+			successful: false,
+		},
+		{
+			golden: "account_merge.json",
+			operation: xdr.Operation{
+				Body: xdr.OperationBody{
+					Type:        xdr.OperationTypeAccountMerge,
+					Destination: &muxedOpSource,
+				},
+			},
+			successful: true,
+		},
+	} {
+		t.Run(testCase.golden, func(t *testing.T) {
+			transaction := participantsTransaction(testCase.successful, testCase.operation)
+			if testCase.result != nil {
+				transaction.Result.Result.Result.Results = &[]xdr.OperationResult{*testCase.result}
+			}
+
+			actual, err := MarshalOperationJSON(transaction, 0)
+			require.NoError(t, err)
+
+			expected, err := ioutil.ReadFile(filepath.Join("testdata", "operation_json", testCase.golden))
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestMarshalOperationJSONErrors(t *testing.T) {
+	transaction := participantsTransaction(true, xdr.Operation{
+		Body: xdr.OperationBody{
+			Type:         xdr.OperationTypeManageData,
+			ManageDataOp: &xdr.ManageDataOp{DataName: "name"},
+		},
+	})
+
+	_, err := MarshalOperationJSON(transaction, 0)
+	assert.EqualError(t, err, "unsupported operation type: 10")
+
+	_, err = MarshalOperationJSON(transaction, 1)
+	assert.EqualError(t, err, "operation index out of range: 1")
+}
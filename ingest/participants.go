@@ -0,0 +1,613 @@
+package ingest
+
+import (
+	"github.com/stellar/go/support/errors"
+	"github.com/stellar/go/xdr"
+)
+
+// ParticipantOptions configures how the participants of a transaction are
+// reported.
+type ParticipantOptions struct {
+	// PreserveMuxed reports muxed accounts using their M... address instead of
+	// collapsing them to the G... address of the underlying account.
+	PreserveMuxed bool
+}
+
+// GetTransactionParticipants returns the accounts taking part in any of the
+// operations of the given transaction, including the ones returned by
+// GetSponsorParticipants, followed by the fee account of fee bump
+// transactions. Each account is returned only once, in the order it was first
+// seen.
+func GetTransactionParticipants(transaction LedgerTransaction) ([]xdr.AccountId, error) {
+	participants, err := participantsForTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	return dedupeParticipants(participants), nil
+}
+
+// GetPaymentParticipants returns the accounts taking part in the payment
+// operations (create account, payments, path payments and account merges) of
+// the given transaction. Each account is returned only once, in the order it
+// was first seen.
+func GetPaymentParticipants(transaction LedgerTransaction) ([]xdr.AccountId, error) {
+	participants, err := participantsForOperations(transaction, true)
+	if err != nil {
+		return nil, err
+	}
+	return dedupeParticipants(participants), nil
+}
+
+// GetTransactionParticipantAddresses is like GetTransactionParticipants but
+// returns strkey addresses formatted according to opts.
+func GetTransactionParticipantAddresses(transaction LedgerTransaction, opts ParticipantOptions) ([]string, error) {
+	participants, err := participantsForTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	return participantAddresses(participants, opts), nil
+}
+
+// GetPaymentParticipantAddresses is like GetPaymentParticipants but returns
+// strkey addresses formatted according to opts.
+func GetPaymentParticipantAddresses(transaction LedgerTransaction, opts ParticipantOptions) ([]string, error) {
+	participants, err := participantsForOperations(transaction, true)
+	if err != nil {
+		return nil, err
+	}
+	return participantAddresses(participants, opts), nil
+}
+
+// GetOperationParticipants returns the accounts taking part in the operation at
+// index opIndex of the given transaction, including the ones returned by
+// GetSponsorParticipants for that operation. The transaction is needed since
+// some participants depend on the other operations (e.g. the sponsor of an
+// EndSponsoringFutureReserves operation) or on the meta. The participants of
+// every operation together are the ones of GetTransactionParticipants, except
+// for the fee account of fee bump transactions.
+func GetOperationParticipants(transaction LedgerTransaction, opIndex int) ([]xdr.AccountId, error) {
+	participants, err := participantsForOperation(transaction, opIndex)
+	if err != nil {
+		return nil, err
+	}
+	return dedupeParticipants(participants), nil
+}
+
+// GetOperationParticipantAddresses is like GetOperationParticipants but
+// returns strkey addresses formatted according to opts.
+func GetOperationParticipantAddresses(transaction LedgerTransaction, opIndex int, opts ParticipantOptions) ([]string, error) {
+	participants, err := participantsForOperation(transaction, opIndex)
+	if err != nil {
+		return nil, err
+	}
+	return participantAddresses(participants, opts), nil
+}
+
+// GetSponsorParticipants returns the sponsors and the sponsored accounts of
+// the ledger entries whose sponsorship was established, transferred or removed
+// by the operations of the given transaction. Sponsorships are only visible in
+// the transaction meta, so no participants are returned for transactions
+// without it (e.g. the ones read from history archives).
+func GetSponsorParticipants(transaction LedgerTransaction) ([]xdr.AccountId, error) {
+	var participants []xdr.MuxedAccount
+
+	for opIndex := range transaction.Envelope.Operations() {
+		opParticipants, err := operationSponsorParticipants(transaction, opIndex)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, opParticipants...)
+	}
+
+	return dedupeParticipants(participants), nil
+}
+
+// GetInflationParticipants returns the accounts credited by the inflation
+// operations of the given transaction. Payouts are only found in the operation
+// results, so these accounts are not returned by GetTransactionParticipants.
+// Inflation was removed in protocol 12 so only older ledgers have any.
+func GetInflationParticipants(transaction LedgerTransaction) ([]xdr.AccountId, error) {
+	if !transaction.Result.Successful() {
+		return nil, nil
+	}
+
+	var participants []xdr.MuxedAccount
+	for opIndex, operation := range transaction.Envelope.Operations() {
+		if operation.Body.Type != xdr.OperationTypeInflation {
+			continue
+		}
+
+		result, err := operationResult(transaction, opIndex)
+		if err != nil {
+			return nil, err
+		}
+		payouts, _ := result.MustInflationResult().GetPayouts()
+		for _, payout := range payouts {
+			participants = append(participants, accountParticipant(payout.Destination))
+		}
+	}
+
+	return dedupeParticipants(participants), nil
+}
+
+// GetSignerParticipants returns the accounts whose signers were added, updated
+// or removed by the set options operations of the given transaction, along
+// with the accounts used as signers. Signers can be arbitrary accounts, which
+// is why they aren't returned by GetTransactionParticipants. Pre-authorized
+// transaction and hash signers aren't accounts and are skipped.
+func GetSignerParticipants(transaction LedgerTransaction) ([]xdr.AccountId, error) {
+	if !transaction.Result.Successful() {
+		return nil, nil
+	}
+
+	var participants []xdr.MuxedAccount
+	for _, operation := range transaction.Envelope.Operations() {
+		op, ok := operation.Body.GetSetOptionsOp()
+		if !ok || op.Signer == nil {
+			continue
+		}
+		participants = append(participants, operationSourceAccount(transaction, operation))
+		if signer, ok := signerAccount(op.Signer.Key); ok {
+			participants = append(participants, accountParticipant(signer))
+		}
+	}
+
+	return dedupeParticipants(participants), nil
+}
+
+// GetTrustLineIssuerParticipants returns the issuers of the assets whose trust
+// lines are changed by the change trust, allow trust and set trust line flags
+// operations of the given transaction. Like GetTransactionParticipants, failed
+// transactions are included. Native assets and liquidity pool shares have no
+// issuer.
+func GetTrustLineIssuerParticipants(transaction LedgerTransaction) ([]xdr.AccountId, error) {
+	var participants []xdr.MuxedAccount
+
+	for _, operation := range transaction.Envelope.Operations() {
+		switch operation.Body.Type {
+		case xdr.OperationTypeChangeTrust:
+			line := operation.Body.MustChangeTrustOp().Line
+			if line.Type == xdr.AssetTypeAssetTypePoolShare {
+				continue
+			}
+			if issuer, ok := assetIssuer(line.ToAsset()); ok {
+				participants = append(participants, accountParticipant(issuer))
+			}
+		case xdr.OperationTypeAllowTrust:
+			// the issuer is the source account
+			participants = append(participants, operationSourceAccount(transaction, operation))
+		case xdr.OperationTypeSetTrustLineFlags:
+			if issuer, ok := assetIssuer(operation.Body.MustSetTrustLineFlagsOp().Asset); ok {
+				participants = append(participants, accountParticipant(issuer))
+			}
+		}
+	}
+
+	return dedupeParticipants(participants), nil
+}
+
+// participantsForTransaction returns the participants of every operation of
+// the given transaction followed by the account paying the fee of fee bump
+// transactions, which is not the source account of the inner transaction.
+func participantsForTransaction(transaction LedgerTransaction) ([]xdr.MuxedAccount, error) {
+	participants, err := participantsForOperations(transaction, false)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Envelope.IsFeeBump() {
+		participants = append(participants, transaction.Envelope.FeeBumpAccount())
+	}
+	return participants, nil
+}
+
+func participantsForOperation(transaction LedgerTransaction, opIndex int) ([]xdr.MuxedAccount, error) {
+	operation, ok := transaction.GetOperation(uint32(opIndex))
+	if !ok {
+		return nil, errors.Errorf("operation index out of range: %d", opIndex)
+	}
+	return operationAndSponsorParticipants(transaction, opIndex, operation)
+}
+
+func participantsForOperations(transaction LedgerTransaction, onlyPayments bool) ([]xdr.MuxedAccount, error) {
+	var participants []xdr.MuxedAccount
+
+	for opIndex, operation := range transaction.Envelope.Operations() {
+		if onlyPayments && !IsPaymentOperation(operation) {
+			continue
+		}
+
+		var opParticipants []xdr.MuxedAccount
+		var err error
+		if onlyPayments {
+			opParticipants, err = operationParticipants(transaction, opIndex, operation)
+		} else {
+			opParticipants, err = operationAndSponsorParticipants(transaction, opIndex, operation)
+		}
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, opParticipants...)
+	}
+
+	return participants, nil
+}
+
+// operationAndSponsorParticipants returns the participants of the operation at
+// index opIndex followed by its sponsor participants.
+func operationAndSponsorParticipants(transaction LedgerTransaction, opIndex int, operation xdr.Operation) ([]xdr.MuxedAccount, error) {
+	participants, err := operationParticipants(transaction, opIndex, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	sponsorParticipants, err := operationSponsorParticipants(transaction, opIndex)
+	if err != nil {
+		return nil, err
+	}
+	return append(participants, sponsorParticipants...), nil
+}
+
+// IsPaymentOperation returns true for the operations which move funds between
+// accounts: create account, payments, path payments and account merges. These
+// are the operations considered by GetPaymentParticipants.
+func IsPaymentOperation(operation xdr.Operation) bool {
+	switch operation.Body.Type {
+	case xdr.OperationTypeCreateAccount,
+		xdr.OperationTypePayment,
+		xdr.OperationTypePathPaymentStrictReceive,
+		xdr.OperationTypePathPaymentStrictSend,
+		xdr.OperationTypeAccountMerge:
+		return true
+	default:
+		return false
+	}
+}
+
+// operationParticipants returns the accounts taking part in the operation at
+// index opIndex of the given transaction, starting with its source account.
+// Muxed accounts are returned as they appear in the transaction.
+func operationParticipants(transaction LedgerTransaction, opIndex int, operation xdr.Operation) ([]xdr.MuxedAccount, error) {
+	participants := []xdr.MuxedAccount{operationSourceAccount(transaction, operation)}
+
+	switch operation.Body.Type {
+	case xdr.OperationTypeCreateAccount:
+		participants = append(participants, accountParticipant(operation.Body.MustCreateAccountOp().Destination))
+	case xdr.OperationTypePayment:
+		participants = append(participants, operation.Body.MustPaymentOp().Destination)
+	case xdr.OperationTypePathPaymentStrictReceive:
+		participants = append(participants, operation.Body.MustPathPaymentStrictReceiveOp().Destination)
+	case xdr.OperationTypePathPaymentStrictSend:
+		participants = append(participants, operation.Body.MustPathPaymentStrictSendOp().Destination)
+	case xdr.OperationTypeManageBuyOffer:
+		// the only direct participant is the source_account
+	case xdr.OperationTypeManageSellOffer:
+		// the only direct participant is the source_account
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		// the only direct participant is the source_account
+	case xdr.OperationTypeSetOptions:
+		// the only direct participant is the source_account
+	case xdr.OperationTypeChangeTrust:
+		// the only direct participant is the source_account
+	case xdr.OperationTypeAllowTrust:
+		participants = append(participants, accountParticipant(operation.Body.MustAllowTrustOp().Trustor))
+	case xdr.OperationTypeAccountMerge:
+		participants = append(participants, operation.Body.MustDestination())
+	case xdr.OperationTypeInflation:
+		// the only direct participant is the source_account
+	case xdr.OperationTypeManageData:
+		// the only direct participant is the source_account
+	case xdr.OperationTypeBumpSequence:
+		// the only direct participant is the source_account
+	case xdr.OperationTypeCreateClaimableBalance:
+		for _, c := range operation.Body.MustCreateClaimableBalanceOp().Claimants {
+			participants = append(participants, accountParticipant(c.MustV0().Destination))
+		}
+	case xdr.OperationTypeClaimClaimableBalance:
+		balanceID := operation.Body.MustClaimClaimableBalanceOp().BalanceId
+		balanceParticipants, err := claimableBalanceParticipants(transaction, opIndex, balanceID)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, balanceParticipants...)
+	case xdr.OperationTypeBeginSponsoringFutureReserves:
+		participants = append(participants, accountParticipant(operation.Body.MustBeginSponsoringFutureReservesOp().SponsoredId))
+	case xdr.OperationTypeEndSponsoringFutureReserves:
+		if sponsor := findSponsorOfFutureReserves(transaction, opIndex, operation); sponsor != nil {
+			participants = append(participants, *sponsor)
+		}
+	case xdr.OperationTypeRevokeSponsorship:
+		op := operation.Body.MustRevokeSponsorshipOp()
+		switch op.Type {
+		case xdr.RevokeSponsorshipTypeRevokeSponsorshipLedgerEntry:
+			for _, id := range getLedgerKeyParticipants(*op.LedgerKey) {
+				participants = append(participants, accountParticipant(id))
+			}
+			if op.LedgerKey.Type == xdr.LedgerEntryTypeClaimableBalance {
+				balanceID := op.LedgerKey.MustClaimableBalance().BalanceId
+				balanceParticipants, err := claimableBalanceParticipants(transaction, opIndex, balanceID)
+				if err != nil {
+					return nil, err
+				}
+				participants = append(participants, balanceParticipants...)
+			}
+		case xdr.RevokeSponsorshipTypeRevokeSponsorshipSigner:
+			participants = append(participants, accountParticipant(op.Signer.AccountId))
+			// We don't add signer as a participant because a signer can be arbitrary account.
+			// This can spam successful operations history of any account.
+		}
+	case xdr.OperationTypeClawback:
+		participants = append(participants, operation.Body.MustClawbackOp().From)
+	case xdr.OperationTypeClawbackClaimableBalance:
+		// the only direct participant is the source_account
+	case xdr.OperationTypeSetTrustLineFlags:
+		participants = append(participants, accountParticipant(operation.Body.MustSetTrustLineFlagsOp().Trustor))
+	case xdr.OperationTypeLiquidityPoolDeposit:
+		poolID := operation.Body.MustLiquidityPoolDepositOp().LiquidityPoolId
+		poolParticipants, err := poolShareTrustLineParticipants(transaction, opIndex, poolID)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, poolParticipants...)
+	case xdr.OperationTypeLiquidityPoolWithdraw:
+		poolID := operation.Body.MustLiquidityPoolWithdrawOp().LiquidityPoolId
+		poolParticipants, err := poolShareTrustLineParticipants(transaction, opIndex, poolID)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, poolParticipants...)
+	default:
+		return nil, UnknownOperationTypeError{Type: operation.Body.Type}
+	}
+
+	return participants, nil
+}
+
+// operationSourceAccount returns the source account of the operation, falling
+// back to the source account of the transaction.
+func operationSourceAccount(transaction LedgerTransaction, operation xdr.Operation) xdr.MuxedAccount {
+	if operation.SourceAccount != nil {
+		return *operation.SourceAccount
+	}
+	return transaction.Envelope.SourceAccount()
+}
+
+// findSponsorOfFutureReserves scans backwards from an
+// EndSponsoringFutureReserves operation for the BeginSponsoringFutureReserves
+// operation which initiated the sponsorship, and returns its source account,
+// i.e. the sponsor, like Horizon does. The back-scan of the lighthorizon map
+// job returned the SponsoredId of that operation instead, which is the source
+// of the EndSponsoringFutureReserves operation and thus already a
+// participant, so the sponsor was missed. Only successful transactions are
+// scanned.
+func findSponsorOfFutureReserves(transaction LedgerTransaction, opIndex int, operation xdr.Operation) *xdr.MuxedAccount {
+	if !transaction.Result.Successful() {
+		// Failed transactions may not have a compliant sandwich structure
+		// we can rely on (e.g. invalid nesting or a being operation with the wrong sponsoree ID)
+		// and thus we bail out since we could return incorrect information.
+		return nil
+	}
+
+	sponsoree := operationSourceAccount(transaction, operation).ToAccountId()
+	operations := transaction.Envelope.Operations()
+	for i := opIndex - 1; i >= 0; i-- {
+		if beginOp, ok := operations[i].Body.GetBeginSponsoringFutureReservesOp(); ok &&
+			beginOp.SponsoredId.Address() == sponsoree.Address() {
+			sponsor := operationSourceAccount(transaction, operations[i])
+			return &sponsor
+		}
+	}
+	return nil
+}
+
+// operationSponsorParticipants returns the former and new sponsors, along with
+// the sponsored accounts, of every ledger entry whose sponsorship was changed
+// by the operation at index opIndex of the given transaction.
+func operationSponsorParticipants(transaction LedgerTransaction, opIndex int) ([]xdr.MuxedAccount, error) {
+	if !hasMeta(transaction) {
+		return nil, nil
+	}
+
+	changes, err := transaction.GetOperationChanges(uint32(opIndex))
+	if err != nil {
+		return nil, err
+	}
+
+	var participants []xdr.MuxedAccount
+	for _, change := range changes {
+		var entry *xdr.LedgerEntry
+		var preSponsor, postSponsor *xdr.AccountId
+		if change.Pre != nil {
+			entry = change.Pre
+			preSponsor = change.Pre.SponsoringID()
+		}
+		if change.Post != nil {
+			entry = change.Post
+			postSponsor = change.Post.SponsoringID()
+		}
+		if sameSponsor(preSponsor, postSponsor) {
+			continue
+		}
+
+		for _, sponsor := range []*xdr.AccountId{preSponsor, postSponsor} {
+			if sponsor != nil {
+				participants = append(participants, accountParticipant(*sponsor))
+			}
+		}
+		for _, id := range getLedgerKeyParticipants(entry.LedgerKey()) {
+			participants = append(participants, accountParticipant(id))
+		}
+	}
+
+	return participants, nil
+}
+
+// poolShareTrustLineParticipants returns the accounts whose trust lines for the
+// shares of the given liquidity pool were changed by the operation at index
+// opIndex of the given transaction. Transactions without meta have none.
+func poolShareTrustLineParticipants(transaction LedgerTransaction, opIndex int, poolID xdr.PoolId) ([]xdr.MuxedAccount, error) {
+	if !hasMeta(transaction) {
+		return nil, nil
+	}
+
+	changes, err := transaction.GetOperationChanges(uint32(opIndex))
+	if err != nil {
+		return nil, err
+	}
+
+	var participants []xdr.MuxedAccount
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeTrustline {
+			continue
+		}
+		entry := change.Post
+		if entry == nil {
+			entry = change.Pre
+		}
+		trustLine := entry.Data.MustTrustLine()
+		if trustLine.Asset.Type != xdr.AssetTypeAssetTypePoolShare ||
+			*trustLine.Asset.LiquidityPoolId != poolID {
+			continue
+		}
+		participants = append(participants, accountParticipant(trustLine.AccountId))
+	}
+
+	return participants, nil
+}
+
+// claimableBalanceParticipants returns the sponsor and the claimants of the
+// given claimable balance, as found in the entry claimed or updated by the
+// operation at index opIndex of the given transaction. Transactions without
+// meta have none.
+func claimableBalanceParticipants(transaction LedgerTransaction, opIndex int, balanceID xdr.ClaimableBalanceId) ([]xdr.MuxedAccount, error) {
+	if !hasMeta(transaction) {
+		return nil, nil
+	}
+
+	changes, err := transaction.GetOperationChanges(uint32(opIndex))
+	if err != nil {
+		return nil, err
+	}
+
+	var participants []xdr.MuxedAccount
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeClaimableBalance || change.Pre == nil {
+			continue
+		}
+		balance := change.Pre.Data.MustClaimableBalance()
+		if !sameClaimableBalance(balance.BalanceId, balanceID) {
+			continue
+		}
+		if sponsor := change.Pre.SponsoringID(); sponsor != nil {
+			participants = append(participants, accountParticipant(*sponsor))
+		}
+		for _, claimant := range balance.Claimants {
+			participants = append(participants, accountParticipant(claimant.MustV0().Destination))
+		}
+	}
+
+	return participants, nil
+}
+
+// hasMeta returns false for transactions read without meta (e.g. from history
+// archives). Participants derived from meta all stem from protocols which
+// produce TransactionMeta.V>=1, so a zero V is treated as missing meta.
+func hasMeta(transaction LedgerTransaction) bool {
+	return transaction.UnsafeMeta.V != 0
+}
+
+func sameSponsor(a, b *xdr.AccountId) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equals(*b)
+}
+
+func sameClaimableBalance(a, b xdr.ClaimableBalanceId) bool {
+	return a.Type == b.Type && a.V0 != nil && b.V0 != nil && *a.V0 == *b.V0
+}
+
+func getLedgerKeyParticipants(ledgerKey xdr.LedgerKey) []xdr.AccountId {
+	var result []xdr.AccountId
+	switch ledgerKey.Type {
+	case xdr.LedgerEntryTypeAccount:
+		result = append(result, ledgerKey.Account.AccountId)
+	case xdr.LedgerEntryTypeClaimableBalance:
+		// nothing to do
+	case xdr.LedgerEntryTypeData:
+		result = append(result, ledgerKey.Data.AccountId)
+	case xdr.LedgerEntryTypeOffer:
+		result = append(result, ledgerKey.Offer.SellerId)
+	case xdr.LedgerEntryTypeTrustline:
+		result = append(result, ledgerKey.TrustLine.AccountId)
+	}
+	return result
+}
+
+// assetIssuer returns the issuer of credit assets.
+func assetIssuer(asset xdr.Asset) (xdr.AccountId, bool) {
+	switch asset.Type {
+	case xdr.AssetTypeAssetTypeCreditAlphanum4:
+		return asset.MustAlphaNum4().Issuer, true
+	case xdr.AssetTypeAssetTypeCreditAlphanum12:
+		return asset.MustAlphaNum12().Issuer, true
+	default:
+		return xdr.AccountId{}, false
+	}
+}
+
+// signerAccount returns the account of ed25519 signer keys.
+func signerAccount(key xdr.SignerKey) (xdr.AccountId, bool) {
+	var ed25519 xdr.Uint256
+	switch key.Type {
+	case xdr.SignerKeyTypeSignerKeyTypeEd25519:
+		ed25519 = key.MustEd25519()
+	case xdr.SignerKeyTypeSignerKeyTypeEd25519SignedPayload:
+		ed25519 = key.MustEd25519SignedPayload().Ed25519
+	default:
+		return xdr.AccountId{}, false
+	}
+	return xdr.AccountId{Type: xdr.PublicKeyTypePublicKeyTypeEd25519, Ed25519: &ed25519}, true
+}
+
+func accountParticipant(id xdr.AccountId) xdr.MuxedAccount {
+	return id.ToMuxedAccount()
+}
+
+// dedupeParticipants collapses muxed accounts to their underlying account and
+// removes any duplicate ids, preserving the order in which they were first
+// seen.
+func dedupeParticipants(in []xdr.MuxedAccount) []xdr.AccountId {
+	seen := map[string]bool{}
+	out := make([]xdr.AccountId, 0, len(in))
+	for _, participant := range in {
+		id := participant.ToAccountId()
+		address := id.Address()
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		out = append(out, id)
+	}
+	return out
+}
+
+// participantAddresses formats the participants according to opts and removes
+// any duplicate addresses, preserving the order in which they were first seen.
+func participantAddresses(in []xdr.MuxedAccount, opts ParticipantOptions) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(in))
+	for _, participant := range in {
+		var address string
+		if opts.PreserveMuxed {
+			address = participant.Address()
+		} else {
+			address = participant.ToAccountId().Address()
+		}
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		out = append(out, address)
+	}
+	return out
+}
@@ -0,0 +1,1158 @@
+package ingest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	participantsTxSource = "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"
+	participantsOpSource = "GDRW375MAYR46ODGF2WGANQC2RRZL7O246DYHHCGWTV2RE7IHE2QUQLD"
+	participantsOther    = "GAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSTVY"
+	participantsThird    = "GCCCU34WDY2RATQTOOQKY6SZWU6J5DONY42SWGW2CIXGW4LICAGNRZKX"
+)
+
+func participantsTransaction(successful bool, ops ...xdr.Operation) LedgerTransaction {
+	code := xdr.TransactionResultCodeTxSuccess
+	if !successful {
+		code = xdr.TransactionResultCodeTxFailed
+	}
+	return LedgerTransaction{
+		Index: 1,
+		Envelope: xdr.TransactionEnvelope{
+			Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+			V1: &xdr.TransactionV1Envelope{
+				Tx: xdr.Transaction{
+					SourceAccount: xdr.MustMuxedAddress(participantsTxSource),
+					Operations:    ops,
+				},
+			},
+		},
+		Result: xdr.TransactionResultPair{
+			Result: xdr.TransactionResult{
+				Result: xdr.TransactionResultResult{
+					Code:    code,
+					Results: &[]xdr.OperationResult{},
+				},
+			},
+		},
+	}
+}
+
+func addresses(ids []xdr.AccountId) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.Address())
+	}
+	return out
+}
+
+func muxedAddresses(accounts []xdr.MuxedAccount) []string {
+	out := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		out = append(out, account.Address())
+	}
+	return out
+}
+
+func TestGetTransactionParticipants(t *testing.T) {
+	other := xdr.MustAddress(participantsOther)
+	otherMuxed := xdr.MustMuxedAddress(participantsOther)
+	opSource := xdr.MustMuxedAddress(participantsOpSource)
+
+	for _, testCase := range []struct {
+		name     string
+		body     xdr.OperationBody
+		expected []string
+		payment  bool
+	}{
+		{
+			name: "create account",
+			body: xdr.OperationBody{
+				Type:            xdr.OperationTypeCreateAccount,
+				CreateAccountOp: &xdr.CreateAccountOp{Destination: other},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+			payment:  true,
+		},
+		{
+			name: "payment",
+			body: xdr.OperationBody{
+				Type:      xdr.OperationTypePayment,
+				PaymentOp: &xdr.PaymentOp{Destination: otherMuxed},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+			payment:  true,
+		},
+		{
+			name: "path payment strict receive",
+			body: xdr.OperationBody{
+				Type:                       xdr.OperationTypePathPaymentStrictReceive,
+				PathPaymentStrictReceiveOp: &xdr.PathPaymentStrictReceiveOp{Destination: otherMuxed},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+			payment:  true,
+		},
+		{
+			name: "path payment strict send",
+			body: xdr.OperationBody{
+				Type:                    xdr.OperationTypePathPaymentStrictSend,
+				PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{Destination: otherMuxed},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+			payment:  true,
+		},
+		{
+			name: "account merge",
+			body: xdr.OperationBody{
+				Type:        xdr.OperationTypeAccountMerge,
+				Destination: &otherMuxed,
+			},
+			expected: []string{participantsTxSource, participantsOther},
+			payment:  true,
+		},
+		{
+			name:     "manage buy offer",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeManageBuyOffer, ManageBuyOfferOp: &xdr.ManageBuyOfferOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name:     "manage sell offer",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeManageSellOffer, ManageSellOfferOp: &xdr.ManageSellOfferOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name:     "create passive sell offer",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeCreatePassiveSellOffer, CreatePassiveSellOfferOp: &xdr.CreatePassiveSellOfferOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name:     "set options",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeSetOptions, SetOptionsOp: &xdr.SetOptionsOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name:     "change trust",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeChangeTrust, ChangeTrustOp: &xdr.ChangeTrustOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name: "allow trust",
+			body: xdr.OperationBody{
+				Type:         xdr.OperationTypeAllowTrust,
+				AllowTrustOp: &xdr.AllowTrustOp{Trustor: other},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name:     "inflation",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeInflation},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name:     "manage data",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeManageData, ManageDataOp: &xdr.ManageDataOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name:     "bump sequence",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeBumpSequence, BumpSequenceOp: &xdr.BumpSequenceOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name: "create claimable balance",
+			body: xdr.OperationBody{
+				Type: xdr.OperationTypeCreateClaimableBalance,
+				CreateClaimableBalanceOp: &xdr.CreateClaimableBalanceOp{
+					Claimants: []xdr.Claimant{
+						{
+							Type: xdr.ClaimantTypeClaimantTypeV0,
+							V0:   &xdr.ClaimantV0{Destination: other},
+						},
+						{
+							Type: xdr.ClaimantTypeClaimantTypeV0,
+							V0:   &xdr.ClaimantV0{Destination: xdr.MustAddress(participantsThird)},
+						},
+					},
+				},
+			},
+			expected: []string{participantsTxSource, participantsOther, participantsThird},
+		},
+		{
+			name:     "claim claimable balance",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeClaimClaimableBalance, ClaimClaimableBalanceOp: &xdr.ClaimClaimableBalanceOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name: "begin sponsoring future reserves",
+			body: xdr.OperationBody{
+				Type:                            xdr.OperationTypeBeginSponsoringFutureReserves,
+				BeginSponsoringFutureReservesOp: &xdr.BeginSponsoringFutureReservesOp{SponsoredId: other},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name:     "end sponsoring future reserves without begin",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeEndSponsoringFutureReserves},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name: "revoke sponsorship of a trust line",
+			body: xdr.OperationBody{
+				Type: xdr.OperationTypeRevokeSponsorship,
+				RevokeSponsorshipOp: &xdr.RevokeSponsorshipOp{
+					Type: xdr.RevokeSponsorshipTypeRevokeSponsorshipLedgerEntry,
+					LedgerKey: &xdr.LedgerKey{
+						Type:      xdr.LedgerEntryTypeTrustline,
+						TrustLine: &xdr.LedgerKeyTrustLine{AccountId: other},
+					},
+				},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name: "revoke sponsorship of a signer",
+			body: xdr.OperationBody{
+				Type: xdr.OperationTypeRevokeSponsorship,
+				RevokeSponsorshipOp: &xdr.RevokeSponsorshipOp{
+					Type: xdr.RevokeSponsorshipTypeRevokeSponsorshipSigner,
+					Signer: &xdr.RevokeSponsorshipOpSigner{
+						AccountId: other,
+						SignerKey: xdr.MustSigner(participantsThird),
+					},
+				},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name: "clawback",
+			body: xdr.OperationBody{
+				Type:       xdr.OperationTypeClawback,
+				ClawbackOp: &xdr.ClawbackOp{From: otherMuxed},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name:     "clawback claimable balance",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeClawbackClaimableBalance, ClawbackClaimableBalanceOp: &xdr.ClawbackClaimableBalanceOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name: "set trust line flags",
+			body: xdr.OperationBody{
+				Type:                xdr.OperationTypeSetTrustLineFlags,
+				SetTrustLineFlagsOp: &xdr.SetTrustLineFlagsOp{Trustor: other},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name:     "liquidity pool deposit",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeLiquidityPoolDeposit, LiquidityPoolDepositOp: &xdr.LiquidityPoolDepositOp{}},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name:     "liquidity pool withdraw",
+			body:     xdr.OperationBody{Type: xdr.OperationTypeLiquidityPoolWithdraw, LiquidityPoolWithdrawOp: &xdr.LiquidityPoolWithdrawOp{}},
+			expected: []string{participantsTxSource},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			tx := participantsTransaction(true, xdr.Operation{Body: testCase.body})
+
+			participants, err := GetTransactionParticipants(tx)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, addresses(participants))
+
+			participants, err = GetPaymentParticipants(tx)
+			require.NoError(t, err)
+			if testCase.payment {
+				assert.Equal(t, testCase.expected, addresses(participants))
+			} else {
+				assert.Empty(t, participants)
+			}
+
+			// the operation source account takes precedence over the
+			// transaction source account
+			tx = participantsTransaction(true, xdr.Operation{SourceAccount: &opSource, Body: testCase.body})
+			participants, err = GetTransactionParticipants(tx)
+			require.NoError(t, err)
+			assert.Equal(t, participantsOpSource, participants[0].Address())
+			assert.NotContains(t, addresses(participants), participantsTxSource)
+		})
+	}
+}
+
+func TestIsPaymentOperation(t *testing.T) {
+	payments := map[xdr.OperationType]bool{
+		xdr.OperationTypeCreateAccount:            true,
+		xdr.OperationTypePayment:                  true,
+		xdr.OperationTypePathPaymentStrictReceive: true,
+		xdr.OperationTypePathPaymentStrictSend:    true,
+		xdr.OperationTypeAccountMerge:             true,
+	}
+
+	var opType xdr.OperationType
+	for i := int32(0); opType.ValidEnum(i); i++ {
+		operation := xdr.Operation{Body: xdr.OperationBody{Type: xdr.OperationType(i)}}
+		assert.Equal(t, payments[xdr.OperationType(i)], IsPaymentOperation(operation), xdr.OperationType(i).String())
+	}
+}
+
+func TestGetTransactionParticipantsUnknownOperation(t *testing.T) {
+	tx := participantsTransaction(true, xdr.Operation{Body: xdr.OperationBody{Type: xdr.OperationType(1000)}})
+	_, err := GetTransactionParticipants(tx)
+	assert.EqualError(t, err, "unknown operation type: 1000")
+
+	var unknownErr UnknownOperationTypeError
+	require.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, xdr.OperationType(1000), unknownErr.Type)
+}
+
+func TestGetTransactionParticipantsDeduplicates(t *testing.T) {
+	otherMuxed := xdr.MustMuxedAddress(participantsOther)
+	payment := xdr.Operation{
+		Body: xdr.OperationBody{
+			Type:      xdr.OperationTypePayment,
+			PaymentOp: &xdr.PaymentOp{Destination: otherMuxed},
+		},
+	}
+	tx := participantsTransaction(true, payment, payment, payment)
+
+	participants, err := GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource, participantsOther}, addresses(participants))
+}
+
+func TestGetTransactionParticipantsDeduplicatesAcrossOperations(t *testing.T) {
+	account := xdr.MustMuxedAddress(participantsOther)
+	muxed42 := xdr.MustMuxedAddress("MAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSAAAAAAAAAAAFIGA2")
+	muxed1234 := xdr.MustMuxedAddress("MAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSAAAAAAAAAAE2LP26")
+	payment := func(source, destination xdr.MuxedAccount) xdr.Operation {
+		return xdr.Operation{
+			SourceAccount: &source,
+			Body: xdr.OperationBody{
+				Type:      xdr.OperationTypePayment,
+				PaymentOp: &xdr.PaymentOp{Destination: destination, Asset: xdr.MustNewNativeAsset()},
+			},
+		}
+	}
+	// the same account is referenced by five operations, as a self payment,
+	// as a source, as a destination and through muxed accounts
+	tx := participantsTransaction(true,
+		payment(account, account),
+		payment(account, xdr.MustMuxedAddress(participantsThird)),
+		payment(xdr.MustMuxedAddress(participantsThird), account),
+		payment(muxed42, account),
+		payment(muxed1234, muxed42),
+	)
+
+	participants, err := GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsOther, participantsThird}, addresses(participants))
+
+	participantAddresses, err := GetTransactionParticipantAddresses(tx, ParticipantOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsOther, participantsThird}, participantAddresses)
+
+	// muxed accounts differing only by id are kept apart
+	participantAddresses, err = GetTransactionParticipantAddresses(tx, ParticipantOptions{PreserveMuxed: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsOther, participantsThird, muxed42.Address(), muxed1234.Address()}, participantAddresses)
+}
+
+func TestGetTransactionParticipantsSponsorshipSandwich(t *testing.T) {
+	sponsor := xdr.MustMuxedAddress(participantsOpSource)
+	sponsoree := xdr.MustAddress(participantsOther)
+	sponsoreeMuxed := xdr.MustMuxedAddress(participantsOther)
+	ops := []xdr.Operation{
+		{
+			SourceAccount: &sponsor,
+			Body: xdr.OperationBody{
+				Type:                            xdr.OperationTypeBeginSponsoringFutureReserves,
+				BeginSponsoringFutureReservesOp: &xdr.BeginSponsoringFutureReservesOp{SponsoredId: sponsoree},
+			},
+		},
+		{
+			SourceAccount: &sponsoreeMuxed,
+			Body: xdr.OperationBody{
+				Type:          xdr.OperationTypeChangeTrust,
+				ChangeTrustOp: &xdr.ChangeTrustOp{},
+			},
+		},
+		{
+			SourceAccount: &sponsoreeMuxed,
+			Body:          xdr.OperationBody{Type: xdr.OperationTypeEndSponsoringFutureReserves},
+		},
+	}
+
+	tx := participantsTransaction(true, ops...)
+	opParticipants, err := operationParticipants(tx, 2, ops[2])
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsOther, participantsOpSource}, muxedAddresses(opParticipants))
+
+	participants, err := GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsOpSource, participantsOther}, addresses(participants))
+
+	// the sandwich of a failed transaction can't be trusted
+	tx = participantsTransaction(false, ops...)
+	opParticipants, err = operationParticipants(tx, 2, ops[2])
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsOther}, muxedAddresses(opParticipants))
+}
+
+func TestGetTransactionParticipantAddressesMuxed(t *testing.T) {
+	muxedTxSource := "MC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUAAAAAAAAAAAFIWY2"
+	muxedOpSource := "MDRW375MAYR46ODGF2WGANQC2RRZL7O246DYHHCGWTV2RE7IHE2QUAAAAAAAAAAAA5QJE"
+	muxedOther := "MAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSAAAAAAAAAAE2LP26"
+	muxedThird := "MCCCU34WDY2RATQTOOQKY6SZWU6J5DONY42SWGW2CIXGW4LICAGNQAAAAAAAAAAAAMM5PG"
+
+	opSource := xdr.MustMuxedAddress(muxedOpSource)
+	other := xdr.MustMuxedAddress(muxedOther)
+	third := xdr.MustMuxedAddress(muxedThird)
+
+	for _, testCase := range []struct {
+		name              string
+		op                xdr.Operation
+		expectedMuxed     []string
+		expectedCollapsed []string
+	}{
+		{
+			name: "payment",
+			op: xdr.Operation{
+				Body: xdr.OperationBody{
+					Type:      xdr.OperationTypePayment,
+					PaymentOp: &xdr.PaymentOp{Destination: other},
+				},
+			},
+			expectedMuxed:     []string{muxedTxSource, muxedOther},
+			expectedCollapsed: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name: "path payment strict receive",
+			op: xdr.Operation{
+				Body: xdr.OperationBody{
+					Type:                       xdr.OperationTypePathPaymentStrictReceive,
+					PathPaymentStrictReceiveOp: &xdr.PathPaymentStrictReceiveOp{Destination: other},
+				},
+			},
+			expectedMuxed:     []string{muxedTxSource, muxedOther},
+			expectedCollapsed: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name: "path payment strict send",
+			op: xdr.Operation{
+				Body: xdr.OperationBody{
+					Type:                    xdr.OperationTypePathPaymentStrictSend,
+					PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{Destination: third},
+				},
+			},
+			expectedMuxed:     []string{muxedTxSource, muxedThird},
+			expectedCollapsed: []string{participantsTxSource, participantsThird},
+		},
+		{
+			name: "account merge",
+			op: xdr.Operation{
+				Body: xdr.OperationBody{
+					Type:        xdr.OperationTypeAccountMerge,
+					Destination: &other,
+				},
+			},
+			expectedMuxed:     []string{muxedTxSource, muxedOther},
+			expectedCollapsed: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name: "muxed operation source",
+			op: xdr.Operation{
+				SourceAccount: &opSource,
+				Body: xdr.OperationBody{
+					Type:      xdr.OperationTypePayment,
+					PaymentOp: &xdr.PaymentOp{Destination: xdr.MustMuxedAddress(participantsOther)},
+				},
+			},
+			expectedMuxed:     []string{muxedOpSource, participantsOther},
+			expectedCollapsed: []string{participantsOpSource, participantsOther},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			tx := participantsTransaction(true, testCase.op)
+			tx.Envelope.V1.Tx.SourceAccount = xdr.MustMuxedAddress(muxedTxSource)
+
+			participants, err := GetTransactionParticipantAddresses(tx, ParticipantOptions{PreserveMuxed: true})
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedMuxed, participants)
+
+			participants, err = GetPaymentParticipantAddresses(tx, ParticipantOptions{PreserveMuxed: true})
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedMuxed, participants)
+
+			participants, err = GetTransactionParticipantAddresses(tx, ParticipantOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedCollapsed, participants)
+
+			ids, err := GetTransactionParticipants(tx)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedCollapsed, addresses(ids))
+		})
+	}
+}
+
+func sponsoredTrustLine(owner string, sponsor *xdr.AccountId) xdr.LedgerEntry {
+	entry := xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeTrustline,
+			TrustLine: &xdr.TrustLineEntry{
+				AccountId: xdr.MustAddress(owner),
+				Asset:     xdr.MustNewCreditAsset("USD", participantsOpSource).ToTrustLineAsset(),
+			},
+		},
+	}
+	if sponsor != nil {
+		entry.Ext = xdr.LedgerEntryExt{
+			V:  1,
+			V1: &xdr.LedgerEntryExtensionV1{SponsoringId: sponsor},
+		}
+	}
+	return entry
+}
+
+func TestGetSponsorParticipants(t *testing.T) {
+	sponsor := xdr.MustAddressPtr(participantsThird)
+	newSponsor := xdr.MustAddressPtr(participantsOpSource)
+	unsponsored := sponsoredTrustLine(participantsOther, nil)
+	sponsored := sponsoredTrustLine(participantsOther, sponsor)
+	transferred := sponsoredTrustLine(participantsOther, newSponsor)
+	key := sponsored.LedgerKey()
+
+	for _, testCase := range []struct {
+		name     string
+		changes  xdr.LedgerEntryChanges
+		expected []string
+	}{
+		{
+			name: "created",
+			changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &sponsored},
+			},
+			expected: []string{participantsThird, participantsOther},
+		},
+		{
+			name: "updated to add a sponsor",
+			changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &unsponsored},
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &sponsored},
+			},
+			expected: []string{participantsThird, participantsOther},
+		},
+		{
+			name: "updated to transfer the sponsorship",
+			changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &sponsored},
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &transferred},
+			},
+			expected: []string{participantsThird, participantsOpSource, participantsOther},
+		},
+		{
+			name: "updated keeping the same sponsor",
+			changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &sponsored},
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &sponsored},
+			},
+			expected: []string{},
+		},
+		{
+			name: "updated to revoke the sponsorship",
+			changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &sponsored},
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &unsponsored},
+			},
+			expected: []string{participantsThird, participantsOther},
+		},
+		{
+			name: "removed",
+			changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &sponsored},
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &key},
+			},
+			expected: []string{participantsThird, participantsOther},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			tx := participantsTransaction(true, xdr.Operation{
+				Body: xdr.OperationBody{
+					Type:          xdr.OperationTypeChangeTrust,
+					ChangeTrustOp: &xdr.ChangeTrustOp{},
+				},
+			})
+			tx.UnsafeMeta = xdr.TransactionMeta{
+				V: 2,
+				V2: &xdr.TransactionMetaV2{
+					Operations: []xdr.OperationMeta{{Changes: testCase.changes}},
+				},
+			}
+
+			participants, err := GetSponsorParticipants(tx)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, addresses(participants))
+
+			participants, err = GetTransactionParticipants(tx)
+			require.NoError(t, err)
+			assert.Equal(t, append([]string{participantsTxSource}, testCase.expected...), addresses(participants))
+		})
+	}
+}
+
+func TestGetSponsorParticipantsWithoutMeta(t *testing.T) {
+	tx := participantsTransaction(true, xdr.Operation{
+		Body: xdr.OperationBody{
+			Type:          xdr.OperationTypeChangeTrust,
+			ChangeTrustOp: &xdr.ChangeTrustOp{},
+		},
+	})
+
+	participants, err := GetSponsorParticipants(tx)
+	require.NoError(t, err)
+	assert.Empty(t, participants)
+}
+
+func poolShareTrustLine(owner string, poolID xdr.PoolId) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeTrustline,
+			TrustLine: &xdr.TrustLineEntry{
+				AccountId: xdr.MustAddress(owner),
+				Asset: xdr.TrustLineAsset{
+					Type:            xdr.AssetTypeAssetTypePoolShare,
+					LiquidityPoolId: &poolID,
+				},
+			},
+		},
+	}
+}
+
+func TestGetTransactionParticipantsLiquidityPools(t *testing.T) {
+	poolID := xdr.PoolId{1, 2, 3}
+	otherPoolID := xdr.PoolId{4, 5, 6}
+	created := poolShareTrustLine(participantsOther, poolID)
+	unrelated := poolShareTrustLine(participantsThird, otherPoolID)
+	removed := poolShareTrustLine(participantsOther, poolID)
+	removedKey := removed.LedgerKey()
+
+	deposit := xdr.Operation{
+		Body: xdr.OperationBody{
+			Type:                   xdr.OperationTypeLiquidityPoolDeposit,
+			LiquidityPoolDepositOp: &xdr.LiquidityPoolDepositOp{LiquidityPoolId: poolID},
+		},
+	}
+	withdraw := xdr.Operation{
+		Body: xdr.OperationBody{
+			Type:                    xdr.OperationTypeLiquidityPoolWithdraw,
+			LiquidityPoolWithdrawOp: &xdr.LiquidityPoolWithdrawOp{LiquidityPoolId: poolID},
+		},
+	}
+
+	for _, testCase := range []struct {
+		name     string
+		op       xdr.Operation
+		changes  xdr.LedgerEntryChanges
+		expected []string
+	}{
+		{
+			name: "deposit creating a pool share trust line",
+			op:   deposit,
+			changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &created},
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &unrelated},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name: "withdraw removing a pool share trust line",
+			op:   withdraw,
+			changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &removed},
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &removedKey},
+			},
+			expected: []string{participantsTxSource, participantsOther},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			tx := participantsTransaction(true, testCase.op)
+			participants, err := GetTransactionParticipants(tx)
+			require.NoError(t, err)
+			assert.Equal(t, []string{participantsTxSource}, addresses(participants))
+
+			tx.UnsafeMeta = xdr.TransactionMeta{
+				V: 2,
+				V2: &xdr.TransactionMetaV2{
+					Operations: []xdr.OperationMeta{{Changes: testCase.changes}},
+				},
+			}
+			participants, err = GetTransactionParticipants(tx)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, addresses(participants))
+		})
+	}
+}
+
+// claimableBalanceEntry returns a claimable balance which can be claimed by
+// participantsTxSource and participantsOther.
+func claimableBalanceEntry(balanceID xdr.ClaimableBalanceId, sponsor *xdr.AccountId) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeClaimableBalance,
+			ClaimableBalance: &xdr.ClaimableBalanceEntry{
+				BalanceId: balanceID,
+				Claimants: []xdr.Claimant{
+					{
+						Type: xdr.ClaimantTypeClaimantTypeV0,
+						V0:   &xdr.ClaimantV0{Destination: xdr.MustAddress(participantsTxSource)},
+					},
+					{
+						Type: xdr.ClaimantTypeClaimantTypeV0,
+						V0:   &xdr.ClaimantV0{Destination: xdr.MustAddress(participantsOther)},
+					},
+				},
+				Asset: xdr.MustNewNativeAsset(),
+			},
+		},
+		Ext: xdr.LedgerEntryExt{
+			V:  1,
+			V1: &xdr.LedgerEntryExtensionV1{SponsoringId: sponsor},
+		},
+	}
+}
+
+func TestGetTransactionParticipantsClaimClaimableBalance(t *testing.T) {
+	balanceID := xdr.ClaimableBalanceId{
+		Type: xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0,
+		V0:   &xdr.Hash{1, 2, 3},
+	}
+	balance := claimableBalanceEntry(balanceID, xdr.MustAddressPtr(participantsThird))
+	key := balance.LedgerKey()
+
+	tx := participantsTransaction(true, xdr.Operation{
+		Body: xdr.OperationBody{
+			Type:                    xdr.OperationTypeClaimClaimableBalance,
+			ClaimClaimableBalanceOp: &xdr.ClaimClaimableBalanceOp{BalanceId: balanceID},
+		},
+	})
+
+	// without meta only the source account is known
+	participants, err := GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource}, addresses(participants))
+
+	tx.UnsafeMeta = xdr.TransactionMeta{
+		V: 2,
+		V2: &xdr.TransactionMetaV2{
+			Operations: []xdr.OperationMeta{{Changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &balance},
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &key},
+			}}},
+		},
+	}
+	participants, err = GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource, participantsThird, participantsOther}, addresses(participants))
+
+	// claimants of other balances are ignored
+	tx.Envelope.V1.Tx.Operations[0].Body.ClaimClaimableBalanceOp.BalanceId = xdr.ClaimableBalanceId{
+		Type: xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0,
+		V0:   &xdr.Hash{4, 5, 6},
+	}
+	participants, err = GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource, participantsThird}, addresses(participants))
+}
+
+func TestGetInflationParticipants(t *testing.T) {
+	tx := participantsTransaction(true,
+		xdr.Operation{Body: xdr.OperationBody{Type: xdr.OperationTypeBumpSequence, BumpSequenceOp: &xdr.BumpSequenceOp{}}},
+		xdr.Operation{Body: xdr.OperationBody{Type: xdr.OperationTypeInflation}},
+	)
+	tx.Result.Result.Result.Results = &[]xdr.OperationResult{
+		{
+			Code: xdr.OperationResultCodeOpInner,
+			Tr: &xdr.OperationResultTr{
+				Type:          xdr.OperationTypeBumpSequence,
+				BumpSeqResult: &xdr.BumpSequenceResult{Code: xdr.BumpSequenceResultCodeBumpSequenceSuccess},
+			},
+		},
+		{
+			Code: xdr.OperationResultCodeOpInner,
+			Tr: &xdr.OperationResultTr{
+				Type: xdr.OperationTypeInflation,
+				InflationResult: &xdr.InflationResult{
+					Code: xdr.InflationResultCodeInflationSuccess,
+					Payouts: &[]xdr.InflationPayout{
+						{Destination: xdr.MustAddress(participantsOther), Amount: 100},
+						{Destination: xdr.MustAddress(participantsThird), Amount: 200},
+						{Destination: xdr.MustAddress(participantsOther), Amount: 300},
+					},
+				},
+			},
+		},
+	}
+
+	participants, err := GetInflationParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsOther, participantsThird}, addresses(participants))
+
+	// payouts are not operation participants
+	participants, err = GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource}, addresses(participants))
+
+	// failed transactions have no payouts
+	failed := participantsTransaction(false, xdr.Operation{Body: xdr.OperationBody{Type: xdr.OperationTypeInflation}})
+	participants, err = GetInflationParticipants(failed)
+	require.NoError(t, err)
+	assert.Empty(t, participants)
+
+	missingResults := participantsTransaction(true, xdr.Operation{Body: xdr.OperationBody{Type: xdr.OperationTypeInflation}})
+	_, err = GetInflationParticipants(missingResults)
+	assert.EqualError(t, err, "missing result for operation 0")
+}
+
+func TestGetTransactionParticipantsRevokeClaimableBalanceSponsorship(t *testing.T) {
+	balanceID := xdr.ClaimableBalanceId{
+		Type: xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0,
+		V0:   &xdr.Hash{1, 2, 3},
+	}
+	sponsored := claimableBalanceEntry(balanceID, xdr.MustAddressPtr(participantsThird))
+	transferred := claimableBalanceEntry(balanceID, xdr.MustAddressPtr(participantsOpSource))
+	key := sponsored.LedgerKey()
+	sponsor := xdr.MustMuxedAddress(participantsThird)
+
+	tx := participantsTransaction(true, xdr.Operation{
+		SourceAccount: &sponsor,
+		Body: xdr.OperationBody{
+			Type: xdr.OperationTypeRevokeSponsorship,
+			RevokeSponsorshipOp: &xdr.RevokeSponsorshipOp{
+				Type:      xdr.RevokeSponsorshipTypeRevokeSponsorshipLedgerEntry,
+				LedgerKey: &key,
+			},
+		},
+	})
+
+	// without meta only the source account is known
+	participants, err := GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{participantsThird}, addresses(participants))
+
+	tx.UnsafeMeta = xdr.TransactionMeta{
+		V: 2,
+		V2: &xdr.TransactionMetaV2{
+			Operations: []xdr.OperationMeta{{Changes: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &sponsored},
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &transferred},
+			}}},
+		},
+	}
+	participants, err = GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]string{participantsThird, participantsTxSource, participantsOther, participantsOpSource},
+		addresses(participants),
+	)
+}
+
+func TestGetSignerParticipants(t *testing.T) {
+	setSigner := func(source *xdr.MuxedAccount, key xdr.SignerKey, weight xdr.Uint32) xdr.Operation {
+		return xdr.Operation{
+			SourceAccount: source,
+			Body: xdr.OperationBody{
+				Type:         xdr.OperationTypeSetOptions,
+				SetOptionsOp: &xdr.SetOptionsOp{Signer: &xdr.Signer{Key: key, Weight: weight}},
+			},
+		}
+	}
+	var otherKey, thirdKey xdr.SignerKey
+	require.NoError(t, otherKey.SetAddress(participantsOther))
+	require.NoError(t, thirdKey.SetAddress(participantsThird))
+	hashKey := xdr.SignerKey{Type: xdr.SignerKeyTypeSignerKeyTypeHashX, HashX: &xdr.Uint256{1, 2, 3}}
+	opSource := xdr.MustMuxedAddress(participantsOpSource)
+	homeDomain := xdr.String32("example.com")
+
+	for _, testCase := range []struct {
+		name     string
+		ops      []xdr.Operation
+		expected []string
+	}{
+		{
+			name:     "add signer",
+			ops:      []xdr.Operation{setSigner(nil, otherKey, 1)},
+			expected: []string{participantsTxSource, participantsOther},
+		},
+		{
+			name:     "remove signer",
+			ops:      []xdr.Operation{setSigner(&opSource, thirdKey, 0)},
+			expected: []string{participantsOpSource, participantsThird},
+		},
+		{
+			name:     "hash signer",
+			ops:      []xdr.Operation{setSigner(nil, hashKey, 1)},
+			expected: []string{participantsTxSource},
+		},
+		{
+			name: "set options without signer",
+			ops: []xdr.Operation{{
+				Body: xdr.OperationBody{
+					Type:         xdr.OperationTypeSetOptions,
+					SetOptionsOp: &xdr.SetOptionsOp{HomeDomain: &homeDomain},
+				},
+			}},
+			expected: []string{},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			tx := participantsTransaction(true, testCase.ops...)
+			participants, err := GetSignerParticipants(tx)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, addresses(participants))
+
+			// signers are not operation participants
+			participants, err = GetTransactionParticipants(tx)
+			require.NoError(t, err)
+			assert.NotContains(t, addresses(participants), participantsOther)
+			assert.NotContains(t, addresses(participants), participantsThird)
+
+			// failed transactions don't change signers
+			participants, err = GetSignerParticipants(participantsTransaction(false, testCase.ops...))
+			require.NoError(t, err)
+			assert.Empty(t, participants)
+		})
+	}
+}
+
+func TestGetTrustLineIssuerParticipants(t *testing.T) {
+	changeTrust := func(line xdr.ChangeTrustAsset) xdr.Operation {
+		return xdr.Operation{
+			Body: xdr.OperationBody{
+				Type:          xdr.OperationTypeChangeTrust,
+				ChangeTrustOp: &xdr.ChangeTrustOp{Line: line, Limit: 100},
+			},
+		}
+	}
+	issuer := xdr.MustMuxedAddress(participantsOpSource)
+	usd := xdr.MustNewCreditAsset("USD", participantsOther)
+	longAsset := xdr.MustNewCreditAsset("LONGASSET", participantsThird)
+	poolShare := xdr.ChangeTrustAsset{
+		Type: xdr.AssetTypeAssetTypePoolShare,
+		LiquidityPool: &xdr.LiquidityPoolParameters{
+			Type: xdr.LiquidityPoolTypeLiquidityPoolConstantProduct,
+			ConstantProduct: &xdr.LiquidityPoolConstantProductParameters{
+				AssetA: xdr.MustNewNativeAsset(),
+				AssetB: usd,
+				Fee:    xdr.LiquidityPoolFeeV18,
+			},
+		},
+	}
+
+	for _, testCase := range []struct {
+		name     string
+		op       xdr.Operation
+		expected []string
+	}{
+		{
+			name:     "change trust alphanum4",
+			op:       changeTrust(usd.ToChangeTrustAsset()),
+			expected: []string{participantsOther},
+		},
+		{
+			name:     "change trust alphanum12",
+			op:       changeTrust(longAsset.ToChangeTrustAsset()),
+			expected: []string{participantsThird},
+		},
+		{
+			name:     "change trust pool share",
+			op:       changeTrust(poolShare),
+			expected: []string{},
+		},
+		{
+			name: "allow trust",
+			op: xdr.Operation{
+				SourceAccount: &issuer,
+				Body: xdr.OperationBody{
+					Type: xdr.OperationTypeAllowTrust,
+					AllowTrustOp: &xdr.AllowTrustOp{
+						Trustor: xdr.MustAddress(participantsOther),
+						Asset:   xdr.MustNewAssetCodeFromString("EUR"),
+					},
+				},
+			},
+			expected: []string{participantsOpSource},
+		},
+		{
+			name: "set trust line flags",
+			op: xdr.Operation{
+				Body: xdr.OperationBody{
+					Type: xdr.OperationTypeSetTrustLineFlags,
+					SetTrustLineFlagsOp: &xdr.SetTrustLineFlagsOp{
+						Trustor: xdr.MustAddress(participantsTxSource),
+						Asset:   longAsset,
+					},
+				},
+			},
+			expected: []string{participantsThird},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			participants, err := GetTrustLineIssuerParticipants(participantsTransaction(true, testCase.op))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, addresses(participants))
+		})
+	}
+}
+
+func TestGetOperationParticipants(t *testing.T) {
+	sponsor := xdr.MustMuxedAddress(participantsOpSource)
+	sponsoreeMuxed := xdr.MustMuxedAddress("MAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSAAAAAAAAAAE2LP26")
+	trustLine := sponsoredTrustLine(participantsOther, xdr.MustAddressPtr(participantsOpSource))
+	tx := participantsTransaction(true,
+		xdr.Operation{
+			SourceAccount: &sponsor,
+			Body: xdr.OperationBody{
+				Type:                            xdr.OperationTypeBeginSponsoringFutureReserves,
+				BeginSponsoringFutureReservesOp: &xdr.BeginSponsoringFutureReservesOp{SponsoredId: xdr.MustAddress(participantsOther)},
+			},
+		},
+		xdr.Operation{
+			SourceAccount: &sponsoreeMuxed,
+			Body: xdr.OperationBody{
+				Type:          xdr.OperationTypeChangeTrust,
+				ChangeTrustOp: &xdr.ChangeTrustOp{},
+			},
+		},
+		xdr.Operation{
+			SourceAccount: &sponsoreeMuxed,
+			Body:          xdr.OperationBody{Type: xdr.OperationTypeEndSponsoringFutureReserves},
+		},
+		xdr.Operation{
+			Body: xdr.OperationBody{
+				Type:      xdr.OperationTypePayment,
+				PaymentOp: &xdr.PaymentOp{Destination: xdr.MustMuxedAddress(participantsThird)},
+			},
+		},
+	)
+	tx.UnsafeMeta = xdr.TransactionMeta{
+		V: 2,
+		V2: &xdr.TransactionMetaV2{
+			Operations: []xdr.OperationMeta{
+				{},
+				{Changes: xdr.LedgerEntryChanges{
+					{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &trustLine},
+				}},
+				{},
+				{},
+			},
+		},
+	}
+
+	expected := [][]string{
+		{participantsOpSource, participantsOther},
+		{participantsOther, participantsOpSource},
+		{participantsOther, participantsOpSource},
+		{participantsTxSource, participantsThird},
+	}
+	var union []string
+	seen := map[string]bool{}
+	for opIndex := range tx.Envelope.Operations() {
+		participants, err := GetOperationParticipants(tx, opIndex)
+		require.NoError(t, err)
+		assert.Equal(t, expected[opIndex], addresses(participants))
+		for _, address := range addresses(participants) {
+			if !seen[address] {
+				seen[address] = true
+				union = append(union, address)
+			}
+		}
+	}
+
+	// the union of the participants of each operation is the one of the
+	// transaction
+	participants, err := GetTransactionParticipants(tx)
+	require.NoError(t, err)
+	assert.Equal(t, addresses(participants), union)
+
+	muxedParticipants, err := GetOperationParticipantAddresses(tx, 1, ParticipantOptions{PreserveMuxed: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{sponsoreeMuxed.Address(), participantsOpSource, participantsOther}, muxedParticipants)
+
+	_, err = GetOperationParticipants(tx, 4)
+	assert.EqualError(t, err, "operation index out of range: 4")
+}
+
+func feeBumpTransaction(feeSource xdr.MuxedAccount, ops ...xdr.Operation) LedgerTransaction {
+	inner := participantsTransaction(true, ops...)
+	return LedgerTransaction{
+		Index: 1,
+		Envelope: xdr.TransactionEnvelope{
+			Type: xdr.EnvelopeTypeEnvelopeTypeTxFeeBump,
+			FeeBump: &xdr.FeeBumpTransactionEnvelope{
+				Tx: xdr.FeeBumpTransaction{
+					FeeSource: feeSource,
+					InnerTx: xdr.FeeBumpTransactionInnerTx{
+						Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+						V1:   inner.Envelope.V1,
+					},
+				},
+			},
+		},
+		Result: xdr.TransactionResultPair{
+			Result: xdr.TransactionResult{
+				Result: xdr.TransactionResultResult{
+					Code: xdr.TransactionResultCodeTxFeeBumpInnerSuccess,
+					InnerResultPair: &xdr.InnerTransactionResultPair{
+						Result: xdr.InnerTransactionResult{
+							Result: xdr.InnerTransactionResultResult{
+								Code:    xdr.TransactionResultCodeTxSuccess,
+								Results: &[]xdr.OperationResult{},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGetTransactionParticipantsFeeBump(t *testing.T) {
+	muxedFeeSource := "MDRW375MAYR46ODGF2WGANQC2RRZL7O246DYHHCGWTV2RE7IHE2QUAAAAAAAAAAAA5QJE"
+	payment := xdr.Operation{
+		Body: xdr.OperationBody{
+			Type:      xdr.OperationTypePayment,
+			PaymentOp: &xdr.PaymentOp{Destination: xdr.MustMuxedAddress(participantsOther)},
+		},
+	}
+	tx := feeBumpTransaction(xdr.MustMuxedAddress(muxedFeeSource), payment)
+
+	participants, err := GetTransactionParticipants(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource, participantsOther, participantsOpSource}, addresses(participants))
+
+	muxedParticipants, err := GetTransactionParticipantAddresses(tx, ParticipantOptions{PreserveMuxed: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource, participantsOther, muxedFeeSource}, muxedParticipants)
+
+	// the fee account doesn't take part in the payments
+	participants, err = GetPaymentParticipants(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource, participantsOther}, addresses(participants))
+
+	// nor in the operations
+	participants, err = GetOperationParticipants(tx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource, participantsOther}, addresses(participants))
+
+	// an inner transaction paying its own fees has no extra participant
+	tx = feeBumpTransaction(xdr.MustMuxedAddress(participantsTxSource), payment)
+	participants, err = GetTransactionParticipants(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{participantsTxSource, participantsOther}, addresses(participants))
+}
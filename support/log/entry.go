@@ -39,11 +39,51 @@ func (e *Entry) SetLevel(level logrus.Level) {
 }
 
 func (e *Entry) DisableColors() {
-	e.entry.Logger.Formatter.(*logrus.TextFormatter).DisableColors = true
+	if formatter, ok := e.entry.Logger.Formatter.(*logrus.TextFormatter); ok {
+		formatter.DisableColors = true
+	}
 }
 
 func (e *Entry) DisableTimestamp() {
-	e.entry.Logger.Formatter.(*logrus.TextFormatter).DisableTimestamp = true
+	switch formatter := e.entry.Logger.Formatter.(type) {
+	case *logrus.TextFormatter:
+		formatter.DisableTimestamp = true
+	case *jsonFormatter:
+		formatter.DisableTimestamp = true
+	}
+}
+
+// UseJSONFormatter makes the logger render every entry as a single line JSON
+// object. Besides the fields of the entry (like `pid`), the object has the
+// `time`, `level` and `msg` keys and, if service is not empty, a `service`
+// key for the entries which don't set one.
+func (e *Entry) UseJSONFormatter(service string) {
+	e.entry.Logger.SetFormatter(&jsonFormatter{
+		JSONFormatter: logrus.JSONFormatter{TimestampFormat: timestampFormat},
+		service:       service,
+	})
+}
+
+// jsonFormatter is a logrus.JSONFormatter which adds the service field to the
+// entries which don't have one.
+type jsonFormatter struct {
+	logrus.JSONFormatter
+	service string
+}
+
+func (f *jsonFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if _, ok := entry.Data["service"]; f.service == "" || ok {
+		return f.JSONFormatter.Format(entry)
+	}
+
+	// entry.Data may be shared with other entries so it is not modified
+	withService := *entry
+	withService.Data = make(logrus.Fields, len(entry.Data)+1)
+	for key, value := range entry.Data {
+		withService.Data[key] = value
+	}
+	withService.Data["service"] = f.service
+	return f.JSONFormatter.Format(&withService)
 }
 
 // WithField creates a child logger annotated with the provided key value pair.
@@ -52,7 +92,8 @@ func (e *Entry) DisableTimestamp() {
 // include the provided value.
 func (e *Entry) WithField(key string, value interface{}) *Entry {
 	return &Entry{
-		entry: *e.entry.WithField(key, value),
+		entry:   *e.entry.WithField(key, value),
+		sampler: e.sampler,
 	}
 }
 
@@ -60,7 +101,8 @@ func (e *Entry) WithField(key string, value interface{}) *Entry {
 // pairs.
 func (e *Entry) WithFields(fields F) *Entry {
 	return &Entry{
-		entry: *e.entry.WithFields(logrus.Fields(fields)),
+		entry:   *e.entry.WithFields(logrus.Fields(fields)),
+		sampler: e.sampler,
 	}
 }
 
@@ -93,55 +135,57 @@ func (e *Entry) WithStack(stackProvider interface{}) *Entry {
 // `WithError` for the given `error`.
 func (e *Entry) WithError(err error) *Entry {
 	return &Entry{
-		entry: *e.entry.WithError(err),
+		entry:   *e.entry.WithError(err),
+		sampler: e.sampler,
 	}
 }
 
 // Add a context to the log entry.
 func (e *Entry) WithContext(ctx context.Context) *Entry {
 	return &Entry{
-		entry: *e.entry.WithContext(ctx),
+		entry:   *e.entry.WithContext(ctx),
+		sampler: e.sampler,
 	}
 }
 
 // Debugf logs a message at the debug severity.
 func (e *Entry) Debugf(format string, args ...interface{}) {
-	e.entry.Debugf(format, args...)
+	e.logf(logrus.DebugLevel, format, args...)
 }
 
 // Debug logs a message at the debug severity.
 func (e *Entry) Debug(args ...interface{}) {
-	e.entry.Debug(args...)
+	e.log(logrus.DebugLevel, args...)
 }
 
 // Infof logs a message at the Info severity.
 func (e *Entry) Infof(format string, args ...interface{}) {
-	e.entry.Infof(format, args...)
+	e.logf(logrus.InfoLevel, format, args...)
 }
 
 // Info logs a message at the Info severity.
 func (e *Entry) Info(args ...interface{}) {
-	e.entry.Info(args...)
+	e.log(logrus.InfoLevel, args...)
 }
 
 // Warnf logs a message at the Warn severity.
 func (e *Entry) Warnf(format string, args ...interface{}) {
-	e.entry.Warnf(format, args...)
+	e.logf(logrus.WarnLevel, format, args...)
 }
 
 // Warn logs a message at the Warn severity.
 func (e *Entry) Warn(args ...interface{}) {
-	e.entry.Warn(args...)
+	e.log(logrus.WarnLevel, args...)
 }
 
 // Errorf logs a message at the Error severity.
 func (e *Entry) Errorf(format string, args ...interface{}) {
-	e.entry.Errorf(format, args...)
+	e.logf(logrus.ErrorLevel, format, args...)
 }
 
 // Error logs a message at the Error severity.
 func (e *Entry) Error(args ...interface{}) {
-	e.entry.Error(args...)
+	e.log(logrus.ErrorLevel, args...)
 }
 
 // Fatalf logs a message at the Fatal severity.
@@ -168,6 +212,28 @@ func (e *Entry) Print(args ...interface{}) {
 	e.entry.Print(args...)
 }
 
+func (e *Entry) log(level logrus.Level, args ...interface{}) {
+	if e.sampler == nil {
+		e.entry.Log(level, args...)
+		return
+	}
+	message := fmt.Sprint(args...)
+	if entry, ok := e.sample(level, message); ok {
+		entry.entry.Log(level, message)
+	}
+}
+
+func (e *Entry) logf(level logrus.Level, format string, args ...interface{}) {
+	if e.sampler == nil {
+		e.entry.Logf(level, format, args...)
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	if entry, ok := e.sample(level, message); ok {
+		entry.entry.Log(level, message)
+	}
+}
+
 // StartTest shifts this logger into "test" mode, ensuring that log lines will
 // be recorded (rather than outputted).  The returned function concludes the
 // test, switches the logger back into normal mode and returns a slice of all
@@ -2,9 +2,13 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEntry_StartTest(t *testing.T) {
@@ -25,3 +29,53 @@ func TestEntry_StartTest(t *testing.T) {
 	e.Warn("goodbye")
 	assert.Contains(t, out.String(), "goodbye", "output was not logged after test")
 }
+
+func TestEntry_UseJSONFormatter(t *testing.T) {
+	var out bytes.Buffer
+	e := New()
+	e.SetOutput(&out)
+	e.UseJSONFormatter("ingest")
+	e.SetLevel(InfoLevel)
+	// must not panic with a JSON formatter
+	e.DisableColors()
+
+	e.WithField("ledger", 123).Info("hello")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 1)
+
+	var logged map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &logged))
+	assert.Equal(t, "hello", logged["msg"])
+	assert.Equal(t, "info", logged["level"])
+	assert.Equal(t, "ingest", logged["service"])
+	assert.Equal(t, float64(123), logged["ledger"])
+	assert.Contains(t, logged, "pid")
+	_, err := time.Parse(timestampFormat, logged["time"].(string))
+	assert.NoError(t, err)
+
+	out.Reset()
+	e.DisableTimestamp()
+	e.Warn("goodbye")
+	logged = nil
+	require.NoError(t, json.Unmarshal(out.Bytes(), &logged))
+	assert.NotContains(t, logged, "time")
+	assert.Equal(t, "warning", logged["level"])
+	assert.Equal(t, "ingest", logged["service"])
+
+	// the service of the entry takes precedence
+	out.Reset()
+	e.WithField("service", "map").Warn("overridden")
+	logged = nil
+	require.NoError(t, json.Unmarshal(out.Bytes(), &logged))
+	assert.Equal(t, "map", logged["service"])
+
+	// without a service name, there is no service key
+	out.Reset()
+	e.UseJSONFormatter("")
+	e.Warn("anonymous")
+	logged = nil
+	require.NoError(t, json.Unmarshal(out.Bytes(), &logged))
+	assert.NotContains(t, logged, "service")
+	assert.Equal(t, "anonymous", logged["msg"])
+}
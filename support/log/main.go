@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 	"os"
+	"time"
 
 	loggly "github.com/segmentio/go-loggly"
 	"github.com/sirupsen/logrus"
@@ -20,9 +21,13 @@ const (
 	DebugLevel = logrus.DebugLevel
 )
 
+const timestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
 // Entry repre
 type Entry struct {
 	entry logrus.Entry
+	// sampler, if set, drops repeated messages. See Entry.Every.
+	sampler *sampler
 
 	isTesting bool
 }
@@ -43,7 +48,7 @@ func New() *Entry {
 	l := logrus.New()
 	l.Level = logrus.WarnLevel
 	l.Formatter.(*logrus.TextFormatter).FullTimestamp = true
-	l.Formatter.(*logrus.TextFormatter).TimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+	l.Formatter.(*logrus.TextFormatter).TimestampFormat = timestampFormat
 	return &Entry{entry: *logrus.NewEntry(l).WithField("pid", os.Getpid())}
 }
 
@@ -76,10 +81,38 @@ func PushContext(parent context.Context, modFn func(*Entry) *Entry) context.Cont
 	return Set(parent, next)
 }
 
+// FromContext returns the logger bound to the provided context, otherwise
+// providing the default logger. It is the same as Ctx.
+func FromContext(ctx context.Context) *Entry {
+	return Ctx(ctx)
+}
+
+// ContextWithFields derives a new context bound to a logger which includes
+// the provided fields on top of the fields of the logger bound to parent.
+// Every line logged with FromContext(ctx) then includes the fields, e.g.:
+//
+//	ctx = log.ContextWithFields(ctx, log.F{"job": jobIndex, "checkpoint": checkpoint})
+//	log.FromContext(ctx).Info("processing")
+func ContextWithFields(parent context.Context, fields F) context.Context {
+	return Set(parent, Ctx(parent).WithFields(fields))
+}
+
 func SetLevel(level logrus.Level) {
 	DefaultLogger.SetLevel(level)
 }
 
+// SetJSONFormatter makes the default logger render entries as single line
+// JSON objects including the service name. See Entry.UseJSONFormatter.
+func SetJSONFormatter(service string) {
+	DefaultLogger.UseJSONFormatter(service)
+}
+
+// Every returns a child of the default logger which emits a given message at
+// most once per interval. See Entry.Every.
+func Every(interval time.Duration) *Entry {
+	return DefaultLogger.Every(interval)
+}
+
 func WithField(key string, value interface{}) *Entry {
 	result := DefaultLogger.WithField(key, value)
 	return result
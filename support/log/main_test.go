@@ -53,6 +53,35 @@ func TestPushCtx(t *testing.T) {
 	assert.Contains(t, output.String(), "foo=baz")
 }
 
+func TestContextWithFields(t *testing.T) {
+	output := new(bytes.Buffer)
+	l := New()
+	l.DisableColors()
+	l.entry.Logger.Out = output
+	ctx := Set(context.Background(), l.WithField("service", "map"))
+
+	jobCtx := ContextWithFields(ctx, F{"job": 3})
+	checkpointCtx := ContextWithFields(jobCtx, F{"checkpoint": 1023, "job": 4})
+
+	FromContext(checkpointCtx).Warn("nested")
+	assert.Contains(t, output.String(), "service=map")
+	assert.Contains(t, output.String(), "job=4")
+	assert.Contains(t, output.String(), "checkpoint=1023")
+
+	// parent contexts are not modified
+	output.Reset()
+	FromContext(jobCtx).Warn("parent")
+	assert.Contains(t, output.String(), "service=map")
+	assert.Contains(t, output.String(), "job=3")
+	assert.NotContains(t, output.String(), "checkpoint")
+
+	// without a logger, fields are added to the default logger
+	assert.Equal(t, DefaultLogger, FromContext(context.Background()))
+	logger := FromContext(ContextWithFields(context.Background(), F{"job": 1}))
+	assert.Equal(t, 1, logger.entry.Data["job"])
+	assert.Equal(t, DefaultLogger.entry.Logger, logger.entry.Logger)
+}
+
 func TestLoggingStatements(t *testing.T) {
 	output := new(bytes.Buffer)
 	l := New()
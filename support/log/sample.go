@@ -0,0 +1,145 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxSampledMessages is the maximum number of distinct messages a sampler
+// tracks. Once reached, the messages whose window has passed are forgotten and
+// if none has, new messages are logged without being tracked.
+const maxSampledMessages = 1024
+
+// samplers holds one sampler per interval so that every call to Every with
+// the same interval shares the same state, even in hot loops. Messages are
+// keyed by logger and fields (see sampleKey) so unrelated loggers don't
+// suppress each other.
+var samplers = struct {
+	sync.Mutex
+	byInterval map[time.Duration]*sampler
+}{byInterval: map[time.Duration]*sampler{}}
+
+// sampler lets through at most one occurrence of a message per interval and
+// counts the occurrences it suppresses. It is safe for concurrent use.
+type sampler struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mutex    sync.Mutex
+	messages map[string]*sampledMessage
+}
+
+type sampledMessage struct {
+	last       time.Time
+	suppressed int
+}
+
+func newSampler(interval time.Duration) *sampler {
+	return &sampler{
+		interval: interval,
+		now:      time.Now,
+		messages: map[string]*sampledMessage{},
+	}
+}
+
+func samplerFor(interval time.Duration) *sampler {
+	samplers.Lock()
+	defer samplers.Unlock()
+	s, ok := samplers.byInterval[interval]
+	if !ok {
+		s = newSampler(interval)
+		samplers.byInterval[interval] = s
+	}
+	return s
+}
+
+// allow returns true if the message should be logged along with the number
+// of occurrences suppressed since it was last logged.
+func (s *sampler) allow(key string) (bool, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	message, ok := s.messages[key]
+	if ok && now.Sub(message.last) < s.interval {
+		message.suppressed++
+		return false, 0
+	}
+
+	suppressed := 0
+	if ok {
+		suppressed = message.suppressed
+	}
+	if !ok && len(s.messages) >= maxSampledMessages {
+		s.forgetExpired(now)
+		if len(s.messages) >= maxSampledMessages {
+			// Too many distinct messages within the interval, e.g. ones
+			// embedding a ledger sequence. They are unlikely to repeat so
+			// they are logged rather than growing the map.
+			return true, 0
+		}
+	}
+	s.messages[key] = &sampledMessage{last: now}
+	return true, suppressed
+}
+
+func (s *sampler) forgetExpired(now time.Time) {
+	for key, message := range s.messages {
+		if now.Sub(message.last) >= s.interval {
+			delete(s.messages, key)
+		}
+	}
+}
+
+// Every returns a child logger which emits a given message at a given level
+// with given fields at most once per interval. Repeated messages within the
+// interval are dropped and the next message that passes includes the number
+// of dropped messages in the `suppressed` field. Messages with different
+// fields, or logged by unrelated loggers, are sampled separately. Fatal and
+// Panic messages are never dropped.
+//
+//	log.Every(time.Second).WithField("archive", url).Error("archive unavailable")
+func (e *Entry) Every(interval time.Duration) *Entry {
+	return &Entry{
+		entry:   e.entry,
+		sampler: samplerFor(interval),
+	}
+}
+
+// sample returns the entry to log message with, or false if the message must
+// be dropped.
+func (e *Entry) sample(level logrus.Level, message string) (*Entry, bool) {
+	if !e.entry.Logger.IsLevelEnabled(level) {
+		return nil, false
+	}
+	ok, suppressed := e.sampler.allow(e.sampleKey(level, message))
+	if !ok {
+		return nil, false
+	}
+	if suppressed > 0 {
+		return e.WithField("suppressed", suppressed), true
+	}
+	return e, true
+}
+
+// sampleKey identifies a message logged at level by the logger of the entry
+// with the fields of the entry.
+func (e *Entry) sampleKey(level logrus.Level, message string) string {
+	keys := make([]string, 0, len(e.entry.Data))
+	for key := range e.entry.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%p %s:%s", e.entry.Logger, level, message)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, e.entry.Data[key])
+	}
+	return b.String()
+}
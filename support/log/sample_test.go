@@ -0,0 +1,116 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvery(t *testing.T) {
+	now := time.Unix(1000, 0)
+	e := New()
+	e.sampler = newSampler(time.Second)
+	e.sampler.now = func() time.Time { return now }
+
+	done := e.StartTest(InfoLevel)
+	for i := 0; i < 5; i++ {
+		e.Error("archive unavailable")
+	}
+	e.Errorf("archive %s", "unavailable")
+	// different fields, messages and levels are sampled separately
+	e.WithField("ledger", 1).Error("archive unavailable")
+	e.WithField("ledger", 1).Error("archive unavailable")
+	e.Error("other error")
+	e.Warn("archive unavailable")
+	// below the log level, so not counted
+	e.Debug("archive unavailable")
+
+	now = now.Add(time.Second)
+	e.Error("archive unavailable")
+	e.Error("archive unavailable")
+	logged := done()
+
+	require.Len(t, logged, 5)
+	assert.Equal(t, "archive unavailable", logged[0].Message)
+	assert.Equal(t, logrus.ErrorLevel, logged[0].Level)
+	assert.NotContains(t, logged[0].Data, "suppressed")
+	assert.Equal(t, 1, logged[1].Data["ledger"])
+	assert.Equal(t, "other error", logged[2].Message)
+	assert.Equal(t, logrus.WarnLevel, logged[3].Level)
+
+	assert.Equal(t, "archive unavailable", logged[4].Message)
+	assert.NotContains(t, logged[4].Data, "ledger")
+	assert.Equal(t, 5, logged[4].Data["suppressed"])
+}
+
+func TestEveryConcurrent(t *testing.T) {
+	e := New()
+	e.sampler = newSampler(time.Hour)
+
+	done := e.StartTest(InfoLevel)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				e.Error("concurrent error")
+			}
+		}()
+	}
+	wg.Wait()
+	logged := done()
+
+	require.Len(t, logged, 1)
+	assert.Equal(t, "concurrent error", logged[0].Message)
+	key := e.sampleKey(logrus.ErrorLevel, "concurrent error")
+	assert.Equal(t, 20*100-1, e.sampler.messages[key].suppressed)
+}
+
+func TestEveryUnrelatedLoggers(t *testing.T) {
+	first, second := New(), New()
+	doneFirst := first.StartTest(InfoLevel)
+	doneSecond := second.StartTest(InfoLevel)
+
+	// both share the sampler of the interval but not their messages
+	first.Every(time.Hour).Error("archive unavailable")
+	second.Every(time.Hour).Error("archive unavailable")
+	first.Every(time.Hour).Error("archive unavailable")
+
+	assert.Len(t, doneFirst(), 1)
+	assert.Len(t, doneSecond(), 1)
+}
+
+func TestEverySharesState(t *testing.T) {
+	assert.Same(t, New().Every(time.Minute).sampler, Every(time.Minute).sampler)
+	assert.NotSame(t, Every(time.Minute).sampler, Every(time.Second).sampler)
+}
+
+func TestEveryCapsTrackedMessages(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := newSampler(time.Second)
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 2*maxSampledMessages; i++ {
+		ok, _ := s.allow(fmt.Sprintf("checkpoint %d unavailable", i))
+		assert.True(t, ok)
+	}
+	assert.Len(t, s.messages, maxSampledMessages)
+
+	// tracked messages are still sampled
+	ok, _ := s.allow("checkpoint 0 unavailable")
+	assert.False(t, ok)
+
+	// expired messages make room for new ones
+	now = now.Add(time.Second)
+	ok, _ = s.allow("new message")
+	assert.True(t, ok)
+	assert.Len(t, s.messages, 1)
+	ok, _ = s.allow("new message")
+	assert.False(t, ok)
+}
@@ -229,6 +229,13 @@ func main() {
 		"S3 endpoint to use",
 	)
 
+	rootCmd.PersistentFlags().StringVar(
+		&opts.ConnectOpts.GCSEndpoint,
+		"gcsendpoint",
+		"",
+		"Google Cloud Storage endpoint to use",
+	)
+
 	rootCmd.PersistentFlags().BoolVarP(
 		&opts.CommandOpts.DryRun,
 		"dryrun",
@@ -1,21 +1,78 @@
 package xdr
 
+import "fmt"
+
+func (l LedgerCloseMeta) LedgerHeaderHistoryEntry() LedgerHeaderHistoryEntry {
+	switch l.V {
+	case 0:
+		return l.MustV0().LedgerHeader
+	case 1:
+		return l.MustV1().LedgerHeader
+	default:
+		panic(fmt.Sprintf("Unsupported LedgerCloseMeta.V: %d", l.V))
+	}
+}
+
 func (l LedgerCloseMeta) LedgerSequence() uint32 {
-	return uint32(l.MustV0().LedgerHeader.Header.LedgerSeq)
+	return uint32(l.LedgerHeaderHistoryEntry().Header.LedgerSeq)
 }
 
 func (l LedgerCloseMeta) LedgerHash() Hash {
-	return l.MustV0().LedgerHeader.Hash
+	return l.LedgerHeaderHistoryEntry().Hash
 }
 
 func (l LedgerCloseMeta) PreviousLedgerHash() Hash {
-	return l.MustV0().LedgerHeader.Header.PreviousLedgerHash
+	return l.LedgerHeaderHistoryEntry().Header.PreviousLedgerHash
 }
 
 func (l LedgerCloseMeta) ProtocolVersion() uint32 {
-	return uint32(l.MustV0().LedgerHeader.Header.LedgerVersion)
+	return uint32(l.LedgerHeaderHistoryEntry().Header.LedgerVersion)
 }
 
 func (l LedgerCloseMeta) BucketListHash() Hash {
-	return l.MustV0().LedgerHeader.Header.BucketListHash
+	return l.LedgerHeaderHistoryEntry().Header.BucketListHash
+}
+
+// TransactionEnvelopes returns the envelopes of the transaction set. For a
+// generalized transaction set these are the envelopes of every component of
+// every phase, in order.
+func (l LedgerCloseMeta) TransactionEnvelopes() []TransactionEnvelope {
+	switch l.V {
+	case 0:
+		return l.MustV0().TxSet.Txs
+	case 1:
+		var envelopes []TransactionEnvelope
+		for _, phase := range l.MustV1().TxSet.MustV1TxSet().Phases {
+			for _, component := range phase.MustV0Components() {
+				envelopes = append(envelopes, component.MustTxsMaybeDiscountedFee().Txs...)
+			}
+		}
+		return envelopes
+	default:
+		panic(fmt.Sprintf("Unsupported LedgerCloseMeta.V: %d", l.V))
+	}
+}
+
+// TxProcessing returns the results and meta of the transactions, in apply
+// order.
+func (l LedgerCloseMeta) TxProcessing() []TransactionResultMeta {
+	switch l.V {
+	case 0:
+		return l.MustV0().TxProcessing
+	case 1:
+		return l.MustV1().TxProcessing
+	default:
+		panic(fmt.Sprintf("Unsupported LedgerCloseMeta.V: %d", l.V))
+	}
+}
+
+func (l LedgerCloseMeta) UpgradesProcessing() []UpgradeEntryMeta {
+	switch l.V {
+	case 0:
+		return l.MustV0().UpgradesProcessing
+	case 1:
+		return l.MustV1().UpgradesProcessing
+	default:
+		panic(fmt.Sprintf("Unsupported LedgerCloseMeta.V: %d", l.V))
+	}
 }
@@ -15,3 +15,45 @@ func TestLedgerSequence(t *testing.T) {
 	}
 	assert.Equal(t, uint32(23), l.LedgerSequence())
 }
+
+func TestTransactionEnvelopes(t *testing.T) {
+	envelopes := []TransactionEnvelope{
+		{Type: EnvelopeTypeEnvelopeTypeTx, V1: &TransactionV1Envelope{Tx: Transaction{SeqNum: 1}}},
+		{Type: EnvelopeTypeEnvelopeTypeTx, V1: &TransactionV1Envelope{Tx: Transaction{SeqNum: 2}}},
+		{Type: EnvelopeTypeEnvelopeTypeTx, V1: &TransactionV1Envelope{Tx: Transaction{SeqNum: 3}}},
+	}
+
+	v0 := LedgerCloseMeta{
+		V: 0,
+		V0: &LedgerCloseMetaV0{
+			TxSet: TransactionSet{Txs: envelopes},
+		},
+	}
+	assert.Equal(t, envelopes, v0.TransactionEnvelopes())
+
+	component := func(txs []TransactionEnvelope) TxSetComponent {
+		return TxSetComponent{
+			Type:                  TxSetComponentTypeTxsetCompTxsMaybeDiscountedFee,
+			TxsMaybeDiscountedFee: &TxSetComponentTxsMaybeDiscountedFee{Txs: txs},
+		}
+	}
+	v1 := LedgerCloseMeta{
+		V: 1,
+		V1: &LedgerCloseMetaV1{
+			LedgerHeader: LedgerHeaderHistoryEntry{
+				Header: LedgerHeader{LedgerSeq: 23},
+			},
+			TxSet: GeneralizedTransactionSet{
+				V: 1,
+				V1TxSet: &TransactionSetV1{
+					Phases: []TransactionPhase{
+						{V: 0, V0Components: &[]TxSetComponent{component(envelopes[:1]), component(envelopes[1:2])}},
+						{V: 0, V0Components: &[]TxSetComponent{component(envelopes[2:])}},
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, envelopes, v1.TransactionEnvelopes())
+	assert.Equal(t, uint32(23), v1.LedgerSequence())
+}